@@ -0,0 +1,64 @@
+package form
+
+import (
+	"github.com/pkg/errors"
+	"reflect"
+	"strings"
+)
+
+var ErrNotStruct = errors.New("answers can be decoded into a pointer to a struct only")
+
+/*
+	Answers of a form stored by the question names
+*/
+type Answers map[string]interface{}
+
+/*
+	Fills fields of a struct with the answers
+	A field gets an answer by the name from its "form" tag or by its own name ignoring the case,
+	numbers are converted to the type of the field (e.g. int)
+*/
+func (a Answers) Decode(v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	value := ptr.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		answer, ok := a.lookup(field)
+		if !ok {
+			continue
+		}
+		answerValue := reflect.ValueOf(answer)
+		switch {
+		case answerValue.Type().AssignableTo(field.Type):
+			value.Field(i).Set(answerValue)
+		case answerValue.Type().ConvertibleTo(field.Type):
+			value.Field(i).Set(answerValue.Convert(field.Type))
+		default:
+			return errors.Errorf("the answer for field %s can not be stored as %s", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+/*
+	Finds an answer for a field of a struct
+*/
+func (a Answers) lookup(field reflect.StructField) (interface{}, bool) {
+	if name := field.Tag.Get("form"); name != "" {
+		answer, ok := a[name]
+		return answer, ok && answer != nil
+	}
+	for name, answer := range a {
+		if strings.EqualFold(name, field.Name) {
+			return answer, answer != nil
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,188 @@
+package form
+
+/*
+	Form flow is a wizard of typed questions for Telegram
+	Author: Daniil Furmanov
+	License: MIT
+*/
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sync"
+)
+
+var ErrFormIsEmpty = errors.New("form has zero questions")
+
+/*
+	Bot methods that the form relies on, *tb.Bot implements them
+*/
+type Bot interface {
+	Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error)
+}
+
+/*
+	A form is a sequence of questions that are asked one by one,
+	the answers are validated and passed to the submit callback when the last question is answered
+*/
+type Form struct {
+	id        string
+	bot       Bot
+	questions []*Question
+	sessions  map[string]*session
+	onSubmit  func(to tb.Recipient, answers Answers)
+	progress  string
+	retry     string
+	mx        sync.RWMutex
+}
+
+/*
+	Answers of a user that are being filled in
+*/
+type session struct {
+	position int
+	answers  Answers
+}
+
+/*
+	Creates a new form flow
+	Questions are prefixed with a progress indicator, e.g. "(1/3) "
+*/
+func NewFormFlow(id string, bot Bot) (*Form, error) {
+	f := &Form{
+		id:       id,
+		bot:      bot,
+		sessions: make(map[string]*session),
+		progress: "(%d/%d) ",
+		retry:    "Please try again",
+		mx:       sync.RWMutex{},
+	}
+	return f, nil
+}
+
+/*
+	Get form's unique identificator
+*/
+func (f *Form) GetId() string {
+	return f.id
+}
+
+/*
+	Get all questions of the form
+*/
+func (f *Form) GetQuestions() []*Question {
+	return f.questions
+}
+
+/*
+	Sets a callback that receives the answers when a user has answered all questions
+*/
+func (f *Form) OnSubmit(handler func(to tb.Recipient, answers Answers)) *Form {
+	f.onSubmit = handler
+	return f
+}
+
+/*
+	Sets a format of the progress indicator that takes the number of the question and the number of all questions
+	An empty format disables the indicator
+*/
+func (f *Form) SetProgress(format string) *Form {
+	f.progress = format
+	return f
+}
+
+/*
+	Sets a default text that is sent when an answer is not accepted
+*/
+func (f *Form) SetRetry(text string) *Form {
+	f.retry = text
+	return f
+}
+
+/*
+	Starts filling in the form for the user by asking the first question
+	Answers of an unfinished attempt are discarded
+*/
+func (f *Form) Start(to tb.Recipient) error {
+	if len(f.questions) < 1 {
+		return ErrFormIsEmpty
+	}
+	if err := f.ask(to, 0); err != nil {
+		return err
+	}
+	f.mx.Lock()
+	f.sessions[to.Recipient()] = &session{answers: make(Answers)}
+	f.mx.Unlock()
+	return nil
+}
+
+/*
+	Stops filling in the form for the user, the answers are discarded
+*/
+func (f *Form) Cancel(to tb.Recipient) {
+	f.mx.Lock()
+	delete(f.sessions, to.Recipient())
+	f.mx.Unlock()
+}
+
+/*
+	Checks if the user is filling in the form
+*/
+func (f *Form) IsActive(of tb.Recipient) bool {
+	f.mx.RLock()
+	_, ok := f.sessions[of.Recipient()]
+	f.mx.RUnlock()
+	return ok
+}
+
+/*
+	Process a message with an answer to the current question
+	An invalid answer is asked again, the last answer submits the form
+	Returns true only if the message was consumed by the form
+*/
+func (f *Form) Process(m *tb.Message) bool {
+	if m == nil || m.Sender == nil {
+		return false
+	}
+	f.mx.RLock()
+	s, ok := f.sessions[m.Sender.Recipient()]
+	f.mx.RUnlock()
+	if !ok {
+		return false
+	}
+	q := f.questions[s.position]
+	value, err := q.parse(m)
+	if err == nil && q.validate != nil {
+		err = q.validate(value)
+	}
+	if err != nil {
+		f.bot.Send(m.Sender, q.retryText(f.retry, err), q.keyboard())
+		return true
+	}
+	s.answers[q.name] = value
+	if s.position+1 < len(f.questions) {
+		if f.ask(m.Sender, s.position+1) == nil {
+			s.position++
+		}
+		return true
+	}
+	f.Cancel(m.Sender)
+	if f.onSubmit != nil {
+		f.onSubmit(m.Sender, s.answers)
+	}
+	return true
+}
+
+/*
+	Sends a question with the progress indicator
+*/
+func (f *Form) ask(to tb.Recipient, position int) error {
+	q := f.questions[position]
+	text := q.prompt
+	if f.progress != "" {
+		text = fmt.Sprintf(f.progress, position+1, len(f.questions)) + text
+	}
+	_, err := f.bot.Send(to, text, q.keyboard())
+	return err
+}
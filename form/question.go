@@ -0,0 +1,210 @@
+package form
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"strings"
+)
+
+/*
+	A type of an answer that a question expects
+*/
+type Kind int
+
+const (
+	Text Kind = iota
+	Number
+	Choice
+	Photo
+	Location
+)
+
+var (
+	ErrUnexpectedAnswer = errors.New("the message is not an answer of the expected kind")
+	ErrNotANumber       = errors.New("the answer is not a number")
+	ErrUnknownChoice    = errors.New("the answer is not one of the choices")
+)
+
+/*
+	A question of a form
+	Answers are stored by the question's name as a string for text and choice questions,
+	a float64 for numbers, *tb.Photo for photos and *tb.Location for locations
+*/
+type Question struct {
+	form     *Form
+	name     string
+	prompt   string
+	kind     Kind
+	choices  []string
+	button   string
+	retry    string
+	validate func(value interface{}) error
+}
+
+/*
+	Adds a question that expects a text
+*/
+func (f *Form) AddText(name, prompt string) *Question {
+	return f.add(name, prompt, Text)
+}
+
+/*
+	Adds a question that expects a number
+*/
+func (f *Form) AddNumber(name, prompt string) *Question {
+	return f.add(name, prompt, Number)
+}
+
+/*
+	Adds a question that expects one of the choices, which are displayed as a reply keyboard
+*/
+func (f *Form) AddChoice(name, prompt string, choices ...string) *Question {
+	q := f.add(name, prompt, Choice)
+	q.choices = choices
+	return q
+}
+
+/*
+	Adds a question that expects a photo
+*/
+func (f *Form) AddPhoto(name, prompt string) *Question {
+	return f.add(name, prompt, Photo)
+}
+
+/*
+	Adds a question that expects a location, which can be shared with a button below the question
+*/
+func (f *Form) AddLocation(name, prompt string) *Question {
+	q := f.add(name, prompt, Location)
+	q.button = "Share location"
+	return q
+}
+
+/*
+	Creates a new question at the end of the form
+*/
+func (f *Form) add(name, prompt string, kind Kind) *Question {
+	q := &Question{form: f, name: name, prompt: prompt, kind: kind}
+	f.questions = append(f.questions, q)
+	return q
+}
+
+/*
+	Get related form
+*/
+func (q *Question) GetForm() *Form {
+	return q.form
+}
+
+/*
+	Get question's name that the answer is stored by
+*/
+func (q *Question) GetName() string {
+	return q.name
+}
+
+/*
+	Get the type of the expected answer
+*/
+func (q *Question) GetKind() Kind {
+	return q.kind
+}
+
+/*
+	Sets a function that checks an answer
+	A rejected answer is asked again with the text of the error, unless the question has a retry text
+	Returns the current question
+*/
+func (q *Question) SetValidator(validate func(value interface{}) error) *Question {
+	q.validate = validate
+	return q
+}
+
+/*
+	Sets a text that is sent when an answer to the question is not accepted
+	Returns the current question
+*/
+func (q *Question) SetRetry(text string) *Question {
+	q.retry = text
+	return q
+}
+
+/*
+	Sets a label of the button that shares a location
+	Returns the current question
+*/
+func (q *Question) SetButton(text string) *Question {
+	q.button = text
+	return q
+}
+
+/*
+	Gets an answer to the question from a message
+*/
+func (q *Question) parse(m *tb.Message) (interface{}, error) {
+	switch q.kind {
+	case Number:
+		if m.Text == "" {
+			return nil, ErrUnexpectedAnswer
+		}
+		number, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(m.Text), ",", ".", 1), 64)
+		if err != nil {
+			return nil, ErrNotANumber
+		}
+		return number, nil
+	case Choice:
+		for _, choice := range q.choices {
+			if choice == m.Text {
+				return choice, nil
+			}
+		}
+		return nil, ErrUnknownChoice
+	case Photo:
+		if m.Photo == nil {
+			return nil, ErrUnexpectedAnswer
+		}
+		return m.Photo, nil
+	case Location:
+		if m.Location == nil {
+			return nil, ErrUnexpectedAnswer
+		}
+		return m.Location, nil
+	}
+	if m.Text == "" {
+		return nil, ErrUnexpectedAnswer
+	}
+	return m.Text, nil
+}
+
+/*
+	Gets a keyboard that is displayed with the question
+*/
+func (q *Question) keyboard() *tb.ReplyMarkup {
+	switch q.kind {
+	case Choice:
+		rows := make([][]tb.ReplyButton, len(q.choices))
+		for i, choice := range q.choices {
+			rows[i] = []tb.ReplyButton{{Text: choice}}
+		}
+		return &tb.ReplyMarkup{ReplyKeyboard: rows, ResizeReplyKeyboard: true, OneTimeKeyboard: true}
+	case Location:
+		rows := [][]tb.ReplyButton{{{Text: q.button, Location: true}}}
+		return &tb.ReplyMarkup{ReplyKeyboard: rows, ResizeReplyKeyboard: true, OneTimeKeyboard: true}
+	}
+	return &tb.ReplyMarkup{ReplyKeyboardRemove: true}
+}
+
+/*
+	Gets a text that is sent when an answer is not accepted
+	Errors of validators are displayed as is
+*/
+func (q *Question) retryText(fallback string, err error) string {
+	if q.retry != "" {
+		return q.retry
+	}
+	if err != ErrUnexpectedAnswer && err != ErrNotANumber && err != ErrUnknownChoice {
+		return err.Error()
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	A depth of pages beyond which a menu is considered too deep to navigate
+*/
+const excessiveDepth = 5
+
+/*
+	A report of authoring mistakes in a menu tree
+	Nodes are listed by paths relative to the root (see Menu.Find)
+*/
+type Analysis struct {
+	// leaves with neither an endpoint nor children, a tap on them does nothing
+	DeadEnds []string
+	// nodes that can not be reached by taps from the root (e.g. below a hidden node or a provider),
+	// they can still be opened by commands, deep links or Goto
+	Unreachable []string
+	// nodes at which branches go deeper than the limit of pages
+	TooDeep []string
+	// the largest number of taps needed to reach a node
+	MaxDepth int
+}
+
+/*
+	Checks if the analysis has found any mistakes
+*/
+func (a *Analysis) HasProblems() bool {
+	return len(a.DeadEnds) > 0 || len(a.Unreachable) > 0 || len(a.TooDeep) > 0
+}
+
+/*
+	Lists the mistakes one per line
+*/
+func (a *Analysis) String() string {
+	var b strings.Builder
+	for _, path := range a.DeadEnds {
+		fmt.Fprintf(&b, "%s: dead end\n", path)
+	}
+	for _, path := range a.Unreachable {
+		fmt.Fprintf(&b, "%s: unreachable from the root\n", path)
+	}
+	for _, path := range a.TooDeep {
+		fmt.Fprintf(&b, "%s: deeper than %d pages\n", path, excessiveDepth)
+	}
+	return b.String()
+}
+
+/*
+	Analyzes the tree of the flow for silent dead ends, nodes that can not be reached from the root
+	and branches deeper than 5 pages, to catch mistakes in large trees
+	Unlike Validate the analysis does not depend on locales
+	Provided nodes are generated at display time, so only their parents are analyzed
+*/
+func (f *Menu) Analyze() *Analysis {
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	a := &Analysis{}
+	visited := make(map[*Node]bool)
+	f.root.analyze(0, false, visited, a)
+	if f.upsell != nil && !visited[f.upsell] {
+		a.Unreachable = append(a.Unreachable, f.upsell.relativePath())
+	}
+	return a
+}
+
+/*
+	Analyzes the node and its children
+	Nodes met more than once (e.g. mounted ones or cycles) are analyzed once
+*/
+func (e *Node) analyze(depth int, hidden bool, visited map[*Node]bool, a *Analysis) {
+	if visited[e] {
+		return
+	}
+	visited[e] = true
+	path := e.relativePath()
+	if e.prev != nil && len(e.nodes) == 0 && e.provider == nil && e.endpoint == nil &&
+		e.url == "" && e.query == "" && e.input == nil && e.payment == nil {
+		a.DeadEnds = append(a.DeadEnds, path)
+	}
+	hidden = hidden || e.hidden
+	if hidden {
+		a.Unreachable = append(a.Unreachable, path)
+	} else if depth > a.MaxDepth {
+		a.MaxDepth = depth
+	}
+	if !hidden && depth == excessiveDepth+1 {
+		a.TooDeep = append(a.TooDeep, path)
+	}
+	for _, child := range e.nodes {
+		// static children are ignored while the provider is set
+		child.analyze(depth+1, hidden || e.provider != nil, visited, a)
+	}
+}
@@ -0,0 +1,108 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"time"
+)
+
+const asyncInterval = time.Second
+
+/*
+	Runs a long task in the background for the dialog that a callback was made from
+	Messages sent to the progress channel replace the caption of the menu, it is edited at most once a second
+	with the latest message
+	When the task is done the caption is restored unless it was changed since the progress was displayed,
+	and the dialog goes to the page of the success node
+	or of the failure node if the task has failed, a nil node leaves the dialog on its current page
+	The error of a failed task is passed to the error handler of the flow
+	An endpoint that calls it should return Stay
+*/
+func (e *Node) RunAsync(c *tb.Callback, task func(progress chan<- string) error, success, failure *Node) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	progress := make(chan string, 1)
+	result := make(chan error, 1)
+	go func() {
+		defer close(progress)
+		result <- task(progress)
+	}()
+	go e.watch(c, d, progress, result, success, failure)
+	return nil
+}
+
+/*
+	Displays progress messages of a task and finishes the task when the progress channel is closed
+	The dialog is updated in turn with its taps
+*/
+func (e *Node) watch(c *tb.Callback, d *Dialog, progress <-chan string, result <-chan error, success, failure *Node) {
+	d.mx.RLock()
+	text, key := d.text, d.textKey
+	d.mx.RUnlock()
+	done := d.done()
+	ticker := time.NewTicker(asyncInterval)
+	defer ticker.Stop()
+	var latest, shown string
+	pending := false
+	for progress != nil {
+		select {
+		case message, ok := <-progress:
+			if !ok {
+				progress = nil
+				break
+			}
+			latest, pending = message, true
+		case <-ticker.C:
+			if !pending {
+				break
+			}
+			pending = false
+			d.exclusive(func() {
+				d.mx.Lock()
+				changed := d.setText(latest)
+				d.mx.Unlock()
+				if !changed {
+					return
+				}
+				shown = latest
+				if err := e.redraw(c.Sender, d); err != nil {
+					e.flow.fail(err, c, e)
+				}
+			})
+		case <-done:
+			// the menu is gone, so the rest of the messages are dropped
+			for range progress {
+			}
+			<-result
+			return
+		}
+	}
+	err := <-result
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	d.exclusive(func() {
+		d.mx.Lock()
+		if shown != "" && d.text == shown && d.textKey == "" {
+			d.text, d.textKey = text, key
+		}
+		d.mx.Unlock()
+		next := success
+		if err != nil {
+			next = failure
+		}
+		if next == nil {
+			next = d.Position
+		}
+		if next == nil {
+			next = e.flow.root
+		}
+		if !next.HasPage() && next.prev != nil {
+			next = next.prev
+		}
+		if err := e.update(c.Sender, d, next); err != nil {
+			e.flow.fail(err, c, e)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"go-telegram-flow/menu/menutest"
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Waits until a condition holds for the last message of a user
+*/
+func waitFor(t *testing.T, bot *menutest.Bot, user *tb.User, what string, ok func(msg *tb.Message) bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if msg := bot.Last(user); msg != nil && ok(msg) {
+			return
+		}
+	}
+	t.Fatalf("the menu has not displayed %s", what)
+}
+
+/*
+	Waits until the last message of a user has a text
+*/
+func waitText(t *testing.T, bot *menutest.Bot, user *tb.User, text string) {
+	t.Helper()
+	waitFor(t, bot, user, text, func(msg *tb.Message) bool { return msg.Text == text })
+}
+
+/*
+	Waits until the last message of a user displays a button of a node
+*/
+func waitButton(t *testing.T, bot *menutest.Bot, user *tb.User, node *menu.Node) {
+	t.Helper()
+	waitFor(t, bot, user, node.GetPath(), func(msg *tb.Message) bool {
+		for _, row := range msg.ReplyMarkup.InlineKeyboard {
+			for _, btn := range row {
+				if node.IsButton(btn) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+/*
+	Adds a node that runs a task reporting a progress until it is finished
+*/
+func addTask(flow *menu.Menu, finish <-chan struct{}, success *menu.Node) {
+	flow.GetRoot().Add("work", func(e *menu.Node, c *tb.Callback) int {
+		task := func(progress chan<- string) error {
+			progress <- "50%"
+			<-finish
+			return nil
+		}
+		if err := e.RunAsync(c, task, success, nil); err != nil {
+			panic(err)
+		}
+		return menu.Stay
+	})
+}
+
+func TestRunAsyncRestoresCaption(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	finish := make(chan struct{})
+	addTask(flow, finish, nil)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.Start(user, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "work"); err != nil {
+		t.Fatal(err)
+	}
+	waitText(t, bot, user, "50%")
+	close(finish)
+	waitText(t, bot, user, "caption")
+}
+
+func TestRunAsyncKeepsChangedCaption(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	finish := make(chan struct{})
+	result := flow.GetRoot().AddSub("result", nil).Add("ok", stay)
+	addTask(flow, finish, result)
+	flow.GetRoot().Add("rename", func(e *menu.Node, c *tb.Callback) int {
+		d, _ := flow.DialogOf(c)
+		d.SetCaption(d.Position, "renamed")
+		return menu.Forward
+	})
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.Start(user, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "work"); err != nil {
+		t.Fatal(err)
+	}
+	waitText(t, bot, user, "50%")
+	if err := bot.Tap(flow, user, "rename"); err != nil {
+		t.Fatal(err)
+	}
+	waitText(t, bot, user, "renamed")
+	close(finish)
+	waitButton(t, bot, user, flow.Find("result/ok"))
+	if text := bot.Last(user).Text; text != "renamed" {
+		t.Fatalf("the changed caption is replaced by %q", text)
+	}
+}
@@ -0,0 +1,111 @@
+package menu
+
+import (
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	An outcome of a tap on a button that is recorded by an audit sink
+*/
+type AuditResult string
+
+const (
+	AuditStay      AuditResult = "stay"
+	AuditForward   AuditResult = "forward"
+	AuditBack      AuditResult = "back"
+	AuditInput     AuditResult = "input"
+	AuditDenied    AuditResult = "denied"
+	AuditDisabled  AuditResult = "disabled"
+	AuditOverQuota AuditResult = "over_quota"
+	AuditCooling   AuditResult = "cooling"
+)
+
+/*
+	A record of a tap on a button of a menu
+	The path of the node is relative to the root (see Menu.Find),
+	the captions are the ones of the menu before and after the tap without any decorations,
+	an error is set if the navigation after the tap has failed
+*/
+type AuditRecord struct {
+	Time          time.Time
+	User          *tb.User
+	Path          string
+	Result        AuditResult
+	CaptionBefore string
+	CaptionAfter  string
+	Err           error
+}
+
+/*
+	Checks if the tap has changed the caption of the menu
+*/
+func (r AuditRecord) CaptionChanged() bool {
+	return r.CaptionBefore != r.CaptionAfter
+}
+
+/*
+	A receiver of records of every tap in a menu (e.g. a writer to an append-only log)
+	Records are sent synchronously after the tap is handled, so the sink should not block
+*/
+type AuditSink interface {
+	Audited(record AuditRecord)
+}
+
+/*
+	Sets a receiver of records of every tap in the menu,
+	so an action trail is kept without instrumenting every endpoint
+*/
+func (f *Menu) WithAudit(sink AuditSink) *Menu {
+	f.audit = sink
+	return f
+}
+
+/*
+	Starts a record of a tap on a node in a dialog
+	Returns a function that completes the record with the result and sends it to the sink,
+	the dialog passed to it is the one the tap has ended in
+*/
+func (f *Menu) auditTap(e *Node, c *tb.Callback, d *Dialog) func(d *Dialog, result AuditResult, err error) {
+	if f.audit == nil {
+		return func(d *Dialog, result AuditResult, err error) {}
+	}
+	record := AuditRecord{Time: time.Now(), User: c.Sender, Path: e.relativePath(), CaptionBefore: d.GetCaption()}
+	return func(d *Dialog, result AuditResult, err error) {
+		record.Result, record.Err = result, err
+		record.CaptionAfter = d.GetCaption()
+		f.audit.Audited(record)
+	}
+}
+
+/*
+	Records a tap that was refused before it reached the node
+*/
+func (f *Menu) auditRefused(e *Node, c *tb.Callback, d *Dialog, result AuditResult) {
+	f.auditTap(e, c, d)(d, result, nil)
+}
+
+/*
+	Converts a result of an endpoint to an audited one
+*/
+func auditResult(result int) AuditResult {
+	switch result {
+	case Forward:
+		return AuditForward
+	case Back:
+		return AuditBack
+	}
+	return AuditStay
+}
+
+/*
+	Converts an action of an auxiliary button to an audited result, buttons that leave the page go back
+*/
+func controlResult(action string) AuditResult {
+	switch action {
+	case "back", "home":
+		return AuditBack
+	}
+	return AuditStay
+}
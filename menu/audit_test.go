@@ -0,0 +1,48 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"reflect"
+	"sync"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A sink that keeps the paths and the results of audited taps
+*/
+type auditLog struct {
+	mx      sync.Mutex
+	records []string
+}
+
+func (l *auditLog) Audited(record menu.AuditRecord) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.records = append(l.records, record.Path+" "+string(record.Result))
+}
+
+func TestAuditTaps(t *testing.T) {
+	flow, bot := newFlow(t, "audit")
+	log := &auditLog{}
+	flow.WithAudit(log).WithAutoBack("back")
+	flow.GetRoot().AddSub("settings", nil).Add("save", stay)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "audit", "en", flow.GetRoot()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"settings", "settings/save"} {
+		if err := bot.Tap(flow, user, path); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	if err := bot.Press(user, "audit/back"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"settings forward", "settings/save stay", "settings back"}
+	if !reflect.DeepEqual(log.records, want) {
+		t.Fatalf("audited %q instead of %q", log.records, want)
+	}
+}
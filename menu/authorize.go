@@ -0,0 +1,50 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+)
+
+const (
+	deniedAlertKey = "not_allowed"
+	deniedAlert    = "You are not allowed to use this option"
+)
+
+/*
+	Sets a function that decides whether a user may use a node (e.g. to keep an admin section in the same tree)
+	Buttons of unauthorized nodes are hidden from menus that have an owner (see Dialog.GetOwner),
+	pressing them anyway is rejected with an alert localized by "<flow id>/not_allowed"
+*/
+func (f *Menu) WithAuthorizer(authorize func(userID int, node *Node) bool) *Menu {
+	f.authorize = authorize
+	return f
+}
+
+/*
+	Checks if the owner of a dialog may see the node's button
+	Menus without an owner display all buttons
+*/
+func (e *Node) isAuthorized(d *Dialog) bool {
+	if e.flow.authorize == nil || d.owner == "" {
+		return true
+	}
+	id, err := strconv.Atoi(d.owner)
+	if err != nil {
+		return true
+	}
+	return e.flow.authorize(id, e)
+}
+
+/*
+	Rejects a press of the node's button made by a user that is not authorized to use it
+	Returns false if the press is allowed
+*/
+func (e *Node) denied(c *tb.Callback, d *Dialog) bool {
+	if e.flow.authorize == nil || c.Sender == nil || e.flow.authorize(c.Sender.ID, e) {
+		return false
+	}
+	if err := e.flow.alert(c, e.flow.translate(d.Language, deniedAlertKey, deniedAlert)); err != nil {
+		e.flow.fail(err, c, e)
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package menu_test
+
+import (
+	"fmt"
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var benchLocales = []string{"en", "ru", "de", "fr", "es", "it", "pt", "uk", "pl", "tr"}
+
+/*
+	Adds a tree of 510 labeled nodes to the root: 10 sections of 5 pages with 9 items each
+*/
+func addTree(flow *menu.Menu) {
+	for i := 0; i < 10; i++ {
+		section := flow.GetRoot().AddSub(fmt.Sprint("section", i), nil).SetLabel(fmt.Sprint("Section ", i))
+		for j := 0; j < 5; j++ {
+			page := section.AddSub(fmt.Sprint("page", j), nil).SetLabel(fmt.Sprint("Page ", j))
+			for k := 0; k < 9; k++ {
+				page.AddSub(fmt.Sprint("item", k), stay).SetLabel(fmt.Sprint("Item ", k))
+			}
+		}
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	flow, _ := newFlow(b, "bench")
+	addTree(flow)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, lang := range benchLocales {
+			flow.Build(lang)
+		}
+	}
+}
+
+func BenchmarkTap(b *testing.B) {
+	flow, bot := newFlow(b, "bench")
+	addTree(flow)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "caption", "en", flow.Find("section0/page0")); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := bot.Tap(flow, user, "section0/page0/item0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A set of Telegram bot methods that the menu relies on
+	*tb.Bot satisfies the interface, other versions of the API can be plugged in with an adapter
+	(see telebot3 package for telebot v3)
+*/
+type Bot interface {
+	Handle(endpoint interface{}, handler interface{})
+	Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error)
+	Edit(message tb.Editable, what interface{}, options ...interface{}) (*tb.Message, error)
+	Delete(message tb.Editable) error
+	Respond(c *tb.Callback, response ...*tb.CallbackResponse) error
+}
@@ -0,0 +1,40 @@
+package menu
+
+import (
+	"sync"
+)
+
+/*
+	Redraws the current page of every open dialog that matches a filter (or of all dialogs if it is nil),
+	so open menus reflect changed data (e.g. prices, stock or translations after a rebuild)
+	Menus are edited one by one, WithSendRate keeps a large broadcast under the limits of Telegram
+	A dialog that is handling a tap is redrawn right after the tap, the filter is called in turn with the tap as well
+	Returns the first error of an edit made before the call returns,
+	the errors are passed to the error handler of the flow as well
+*/
+func (f *Menu) BroadcastRefresh(filter func(d *Dialog) bool) error {
+	dialogs := f.dialogs.list(func(d *Dialog) bool {
+		return d.GetMessage() != nil
+	})
+	var first error
+	var mx sync.Mutex
+	for _, d := range dialogs {
+		d := d
+		d.serialize(func() {
+			if d.Message == nil || filter != nil && !filter(d) {
+				return
+			}
+			if err := f.root.redraw(d.Message.Chat, d); err != nil {
+				f.fail(err, nil, d.Position)
+				mx.Lock()
+				if first == nil {
+					first = err
+				}
+				mx.Unlock()
+			}
+		})
+	}
+	mx.Lock()
+	defer mx.Unlock()
+	return first
+}
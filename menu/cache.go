@@ -0,0 +1,176 @@
+package menu
+
+import (
+	"container/list"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sync"
+)
+
+/*
+	Makes the flow build markups of pages the first time they are displayed in a locale instead of at Build
+	and keep up to a number of them, the least recently displayed markups are dropped when the cache is full
+	and built again on their next display
+	Handlers of the buttons are still registered at Build, so buttons of old messages keep working
+	Must be called before the flow is built, zero size builds every markup upfront
+*/
+func (f *Menu) WithMarkupCache(size int) *Menu {
+	if size <= 0 {
+		f.markupCache = nil
+		return f
+	}
+	f.markupCache = newMarkupCache(size)
+	return f
+}
+
+/*
+	A key of a cached markup
+*/
+type markupKey struct {
+	node *Node
+	key  string
+}
+
+/*
+	A cached markup of a page along with the buttons of the node's children
+*/
+type markupEntry struct {
+	markupKey
+	buttons []tb.InlineButton
+	markup  *tb.ReplyMarkup
+}
+
+/*
+	A least recently used cache of markups
+*/
+type markupCache struct {
+	size    int
+	order   *list.List
+	entries map[markupKey]*list.Element
+	mx      sync.Mutex
+}
+
+/*
+	Creates an empty cache of a size
+*/
+func newMarkupCache(size int) *markupCache {
+	return &markupCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[markupKey]*list.Element),
+	}
+}
+
+/*
+	Gets a cached markup and marks it as recently used
+*/
+func (mc *markupCache) get(key markupKey) (*markupEntry, bool) {
+	mc.mx.Lock()
+	defer mc.mx.Unlock()
+	el, ok := mc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	mc.order.MoveToFront(el)
+	return el.Value.(*markupEntry), true
+}
+
+/*
+	Stores a markup and drops the least recently used ones over the size
+*/
+func (mc *markupCache) put(entry *markupEntry) {
+	mc.mx.Lock()
+	defer mc.mx.Unlock()
+	if el, ok := mc.entries[entry.markupKey]; ok {
+		el.Value = entry
+		mc.order.MoveToFront(el)
+		return
+	}
+	mc.entries[entry.markupKey] = mc.order.PushFront(entry)
+	for mc.order.Len() > mc.size {
+		last := mc.order.Back()
+		mc.order.Remove(last)
+		delete(mc.entries, last.Value.(*markupEntry).markupKey)
+	}
+}
+
+/*
+	Drops every cached markup, so the pages are built anew after the tree has changed
+*/
+func (mc *markupCache) clear() {
+	mc.mx.Lock()
+	defer mc.mx.Unlock()
+	mc.order.Init()
+	mc.entries = make(map[markupKey]*list.Element)
+}
+
+/*
+	Gets buttons of the node's children and a markup of the node's page in a locale
+	The tree has to be locked by the caller
+*/
+func (e *Node) materialized(lang string) ([]tb.InlineButton, *tb.ReplyMarkup) {
+	cache := e.flow.markupCache
+	if cache == nil {
+		return e.buttons[lang], e.markups[lang]
+	}
+	key := markupKey{node: e, key: lang}
+	if entry, ok := cache.get(key); ok {
+		return entry.buttons, entry.markup
+	}
+	buttons, markup := e.materialize(lang)
+	cache.put(&markupEntry{markupKey: key, buttons: buttons, markup: markup})
+	return buttons, markup
+}
+
+/*
+	Gets a markup of the node's page in a variant of the tree
+	The tree has to be locked by the caller
+*/
+func (e *Node) materializedVariant(name, lang string) (*tb.ReplyMarkup, bool) {
+	cache := e.flow.markupCache
+	if cache == nil {
+		markup, ok := e.markups[variantKey(name, lang)]
+		return markup, ok
+	}
+	filter, ok := e.flow.variants[name]
+	if !ok {
+		return nil, false
+	}
+	key := markupKey{node: e, key: variantKey(name, lang)}
+	if entry, ok := cache.get(key); ok {
+		return entry.markup, true
+	}
+	buttons, _ := e.materialized(lang)
+	markup := e.filtered(lang, buttons, filter)
+	cache.put(&markupEntry{markupKey: key, markup: markup})
+	return markup, true
+}
+
+/*
+	Creates buttons of the node's children and a markup of the node's page in a locale
+*/
+func (e *Node) materialize(lang string) ([]tb.InlineButton, *tb.ReplyMarkup) {
+	buttons := make([]tb.InlineButton, len(e.nodes))
+	for i, child := range e.nodes {
+		buttons[i] = child.button(lang, child.label(lang))
+	}
+	_, sorted := e.ordered(nil, e.nodes, buttons)
+	return buttons, &tb.ReplyMarkup{
+		InlineKeyboard: e.decorate(lang, e.arrange(sorted)),
+	}
+}
+
+/*
+	Copies the rows of a markup, so neither decorations for a dialog nor a bot that prepares buttons in place
+	(e.g. telebot prefixes their data) write into a built markup
+*/
+func copyMarkup(markup *tb.ReplyMarkup) *tb.ReplyMarkup {
+	if markup == nil {
+		return nil
+	}
+	copied := *markup
+	copied.InlineKeyboard = make([][]tb.InlineButton, len(markup.InlineKeyboard))
+	for i, row := range markup.InlineKeyboard {
+		copied.InlineKeyboard[i] = append([]tb.InlineButton(nil), row...)
+	}
+	return &copied
+}
@@ -0,0 +1,28 @@
+package menu_test
+
+import (
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestCachedMarkupIsCopied(t *testing.T) {
+	flow, bot := newFlow(t, "cache")
+	flow.WithMarkupCache(10)
+	flow.GetRoot().Add("refresh", stay)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "cache", "en", flow.GetRoot()); err != nil {
+		t.Fatal(err)
+	}
+	// bots may prepare the buttons of a sent markup in place
+	sent := bot.Sends[0].Markup.InlineKeyboard[0]
+	data := sent[0].Data
+	sent[0].Data = "prepared|" + data
+	if err := flow.StartAt(user, "cache", "en", flow.GetRoot()); err != nil {
+		t.Fatal(err)
+	}
+	if got := bot.Sends[len(bot.Sends)-1].Markup.InlineKeyboard[0][0].Data; got != data {
+		t.Fatalf("the cached markup is changed to %q", got)
+	}
+}
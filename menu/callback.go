@@ -0,0 +1,94 @@
+package menu
+
+import (
+	"fmt"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+const (
+	callbackLimit = 64
+	compactPrefix = "~"
+	longestAction = "progress"
+)
+
+/*
+	Gets the size of callback data that Telegram receives for a button with a unique and data
+*/
+func callbackSize(unique, data string) int {
+	// telebot sends "\f<unique>|<data>", the data of the flow's buttons starts with a route
+	return len(unique) + len(data) + 2
+}
+
+/*
+	Gets a route of an auxiliary button of the node (e.g. page navigation) that the flow dispatches its callbacks by
+*/
+func (e *Node) controlRoute(action string) string {
+	return action + "_" + e.id
+}
+
+/*
+	Gets data of the node's button along with its route that fits in the limit of Telegram
+	Longer data is replaced with a hash of it and stored in a table of the flow,
+	the callback gets the original data back before it is handled (see expand)
+	The table is kept in memory, so a compacted button pressed after a restart is handled with the hash
+	until its page is displayed again
+*/
+func (e *Node) compactData() string {
+	data := fitData(e.flow.id, e.id, e.data)
+	if data == e.data {
+		return routeData(e.id, data)
+	}
+	e.flow.mx.Lock()
+	if e.flow.payloads == nil {
+		e.flow.payloads = make(map[string]string)
+	}
+	e.flow.payloads[data] = e.data
+	e.flow.mx.Unlock()
+	return routeData(e.id, data)
+}
+
+/*
+	Gets data as it is if it fits in the limit along with a unique and a route, otherwise a hash of the data
+*/
+func fitData(unique, route, data string) string {
+	if data == "" || callbackSize(unique, data)+len(route)+len(routeSeparator) <= callbackLimit {
+		return data
+	}
+	return compactPrefix + hashId(data, 0)
+}
+
+/*
+	Wraps a handler of a node's button, so it gets data that was compacted by the flow
+*/
+func (f *Menu) expand(handler func(c *tb.Callback)) func(c *tb.Callback) {
+	return func(c *tb.Callback) {
+		if strings.HasPrefix(c.Data, compactPrefix) {
+			f.mx.RLock()
+			data, ok := f.payloads[c.Data]
+			f.mx.RUnlock()
+			if ok {
+				c.Data = data
+			}
+		}
+		handler(c)
+	}
+}
+
+/*
+	Checks that the callback data of the node's buttons fits in the limit of Telegram
+	Returns a description of the problem or an empty string
+*/
+func (e *Node) oversized() string {
+	if e.url != "" || e.query != "" {
+		return ""
+	}
+	size := callbackSize(e.flow.id, routeData(e.id, fitData(e.flow.id, e.id, e.data)))
+	if control := callbackSize(e.flow.id, e.controlRoute(longestAction)); control > size {
+		size = control
+	}
+	if size <= callbackLimit {
+		return ""
+	}
+	return fmt.Sprintf("callback data takes up to %d bytes, Telegram allows %d, the flow id is too long", size, callbackLimit)
+}
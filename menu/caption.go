@@ -0,0 +1,40 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+)
+
+const breadcrumbSeparator = " › "
+
+/*
+	Enables breadcrumbs that prefix the caption of the menu with the localized path of the current page
+	(e.g. "Settings › Notifications"), format must contain a single %s verb for the path
+	Right-to-left locales get the separator reversed (see SetLocaleMeta)
+*/
+func (f *Menu) WithBreadcrumbs(format string) *Menu {
+	f.breadcrumbs = format
+	return f
+}
+
+/*
+	Composes the text of the menu message for a dialog
+*/
+func (f *Menu) caption(d *Dialog) string {
+	text := f.progressCaption(d, d.GetCaption())
+	if f.breadcrumbs == "" || d.Position == nil {
+		return text
+	}
+	lang := f.resolve(d.Language)
+	labels := make([]string, 0)
+	for e := d.Position; e != nil && e.prev != nil; e = e.parent(d) {
+		labels = append(labels, e.label(lang))
+	}
+	if len(labels) < 1 {
+		return text
+	}
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return fmt.Sprintf(f.breadcrumbs, strings.Join(labels, f.separator(lang))) + text
+}
@@ -0,0 +1,182 @@
+package menu
+
+/*
+	Creates a new flow with the same settings and a copy of the tree,
+	so one definition can drive several bots or variants of a menu (e.g. for A/B tests)
+	The nodes of the copy get the same ids as the nodes of the flow, since the ids follow the paths,
+	the copy is built for the same locales as the flow, dialogs are not copied
+	Texts of the copy are localized by its own id the same way as of any flow
+	Nodes of select and radio groups and of widgets keep referring to the nodes of the original tree
+	The same rules as for NewMenuFlow apply to the id, it must differ from the id of the flow if the bot is the same
+*/
+func (f *Menu) Clone(id string, bot Bot) (*Menu, error) {
+	c, err := NewMenuFlow(id, bot, f.engine)
+	if err != nil {
+		return nil, err
+	}
+	f.mx.RLock()
+	c.defaultLocale = f.defaultLocale
+	c.prevLabel, c.nextLabel = f.prevLabel, f.nextLabel
+	c.middlewares = append([]Middleware(nil), f.middlewares...)
+	c.breadcrumbs = f.breadcrumbs
+	c.onStale = f.onStale
+	c.onExpire = f.onExpire
+	c.disabledPrefix, c.disabledAlert = f.disabledPrefix, f.disabledAlert
+	c.reply = f.reply
+	c.backKey, c.homeKey = f.backKey, f.homeKey
+	c.onEnter, c.onLeave, c.onNavigate, c.onClose = f.onEnter, f.onLeave, f.onNavigate, f.onClose
+	c.onError = f.onError
+	c.resend = f.resend
+	c.perMessage, c.groups, c.copyForeign = f.perMessage, f.groups, f.copyForeign
+	c.foreignAlert = f.foreignAlert
+	c.ownerLock = f.ownerLock
+	c.respondTimeout = f.respondTimeout
+	c.lazyLocales = f.lazyLocales
+	c.metrics, c.logger = f.metrics, f.logger
+	c.debounce = f.debounce
+	c.ctx, c.endpointTimeout = f.ctx, f.endpointTimeout
+	c.onShutdown, c.shutdownCaption = f.onShutdown, f.shutdownCaption
+	c.authorize = f.authorize
+	c.keepOnClose, c.closeCaption = f.keepOnClose, f.closeCaption
+	c.retries, c.retryDelay = f.retries, f.retryDelay
+	c.progressButton = f.progressButton
+	c.undoLimit = f.undoLimit
+	c.detectLanguage = f.detectLanguage
+	c.numbered = f.numbered
+	c.quota = f.quota
+	c.audit = f.audit
+	if f.markupCache != nil {
+		c.markupCache = newMarkupCache(f.markupCache.size)
+	}
+	for lang, meta := range f.localeMeta {
+		if c.localeMeta == nil {
+			c.localeMeta = make(map[string]LocaleMeta)
+		}
+		c.localeMeta[lang] = meta
+	}
+	ttl, action := f.ttl, f.idleAction
+	resume, prompt := f.resume, f.resumePrompt
+	f.mx.RUnlock()
+	f.limiter.mx.Lock()
+	c.WithEditLimit(f.limiter.interval, f.limiter.burst)
+	f.limiter.mx.Unlock()
+	if bot == f.bot {
+		c.queue = f.queue
+	} else {
+		f.queue.mx.Lock()
+		c.queue.global, c.queue.perChat = f.queue.global, f.queue.perChat
+		f.queue.mx.Unlock()
+	}
+	if ttl > 0 {
+		c.WithIdleTimeout(ttl, action)
+	}
+	f.tree.RLock()
+	copies := make(map[*Node]*Node)
+	for _, child := range f.root.nodes {
+		if child == f.resumeNode {
+			// the prompt is bound to the flow, so the copy gets its own
+			continue
+		}
+		c.root.nodes = append(c.root.nodes, child.copy(c, c.root, true, copies))
+	}
+	c.root.copySettings(f.root)
+	for name, filter := range f.variants {
+		if c.variants == nil {
+			c.variants = make(map[string]func(*Node) bool)
+		}
+		c.variants[name] = filter
+	}
+	if upsell, ok := copies[f.upsell]; ok {
+		c.upsell = upsell
+	}
+	f.tree.RUnlock()
+	if resume {
+		c.WithResume(prompt)
+	}
+	for _, lang := range f.GetLocales() {
+		c.Build(lang)
+	}
+	return c, nil
+}
+
+/*
+	Binds the flow to one more bot (e.g. per-country bots with identical menus)
+	The returned flow has the same id, settings and tree, its own dialogs, handlers and markups,
+	so the bots reuse the same translations
+	Nodes added to the flow after it was attached are not added to the returned flow
+*/
+func (f *Menu) Attach(bot Bot) (*Menu, error) {
+	return f.Clone(f.id, bot)
+}
+
+/*
+	Creates a copy of the node with a fresh id that is not attached to any parent yet
+	A deep copy has copies of all children, a shallow one shares the children with the node
+	Returns the copy
+*/
+func (e *Node) Clone(deep bool) *Node {
+	e.flow.tree.RLock()
+	defer e.flow.tree.RUnlock()
+	return e.copy(e.flow, e.prev, deep, make(map[*Node]*Node))
+}
+
+/*
+	Copies the node to a flow
+	Nodes that are met more than once (e.g. mounted ones) are copied once
+*/
+func (e *Node) copy(flow *Menu, prev *Node, deep bool, copies map[*Node]*Node) *Node {
+	if c, ok := copies[e]; ok {
+		return c
+	}
+	if e.mounted {
+		prev = flow.root
+	}
+	c := newNode(flow, e.text, e.endpoint, prev)
+	copies[e] = c
+	c.copySettings(e)
+	if e.payment != nil {
+		payment := *e.payment
+		c.payment = &payment
+		flow.addInvoice(c)
+	}
+	if !deep {
+		c.nodes = append([]*Node(nil), e.nodes...)
+		return c
+	}
+	for _, child := range e.nodes {
+		c.nodes = append(c.nodes, child.copy(flow, c, true, copies))
+	}
+	return c
+}
+
+/*
+	Copies the settings of a node that do not depend on its place in a tree
+*/
+func (e *Node) copySettings(from *Node) {
+	e.labelKey = from.labelKey
+	e.raw = from.raw
+	e.provider = from.provider
+	// navigation buttons of pages are registered along with the size
+	e.SetPageSize(from.pageSize)
+	e.layout = from.layout
+	e.input = from.input
+	e.visible = from.visible
+	e.marker = from.marker
+	e.labelFunc = from.labelFunc
+	e.trArgs = from.trArgs
+	e.respondIn = from.respondIn
+	e.url = from.url
+	e.query = from.query
+	e.captionKey = from.captionKey
+	e.media = from.media
+	e.data = from.data
+	e.mounted = from.mounted
+	e.guard = from.guard
+	e.guardAlert = from.guardAlert
+	e.refreshEvery, e.refresher = from.refreshEvery, from.refresher
+	e.sendOptions = from.sendOptions
+	e.experiment, e.variant = from.experiment, from.variant
+	e.title, e.hidden, e.columns, e.endpointName = from.title, from.hidden, from.columns, from.endpointName
+	e.less, e.order, e.search = from.less, from.order, from.search
+	e.cooldown = from.cooldown
+}
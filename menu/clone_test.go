@@ -0,0 +1,57 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"go-telegram-flow/menu/menutest"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Opens the list of a flow and turns its page
+*/
+func turnListPage(t *testing.T, flow *menu.Menu, bot *menutest.Bot) {
+	t.Helper()
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "list", "en", flow.Find("list")); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range bot.Last(user).ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.URL == "" && (btn.Unique == "" || btn.Data == "") {
+				t.Fatalf("button %q has no callback data", btn.Text)
+			}
+		}
+	}
+	if err := bot.Press(user, "»"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "list/item2"); err != nil {
+		t.Fatalf("the next page is not displayed: %v", err)
+	}
+}
+
+func TestClonePaginates(t *testing.T) {
+	flow, _ := newFlow(t, "test")
+	addList(flow, 5, 2)
+	flow.Build("en")
+	bot := menutest.NewBot()
+	clone, err := flow.Clone("copy", bot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turnListPage(t, clone, bot)
+}
+
+func TestAttachPaginates(t *testing.T) {
+	flow, _ := newFlow(t, "test")
+	addList(flow, 5, 2)
+	flow.Build("en")
+	bot := menutest.NewBot()
+	attached, err := flow.Attach(bot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turnListPage(t, attached, bot)
+}
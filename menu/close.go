@@ -0,0 +1,66 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Makes closed menus stay in the chat without buttons instead of being deleted
+	The caption of a closed menu is replaced unless the text is empty
+*/
+func (f *Menu) WithKeepOnClose(text string) *Menu {
+	f.keepOnClose = true
+	f.closeCaption = text
+	return f
+}
+
+/*
+	Closes the menu of a recipient and removes the dialog
+	A dialog that is handling a tap is closed right after the tap,
+	the error of such a close is passed to the error handler of the flow
+*/
+func (f *Menu) Close(to tb.Recipient) error {
+	d, ok := f.GetDialog(to.Recipient())
+	if !ok {
+		return nil
+	}
+	return f.inTurn(d, func() error {
+		return f.close(to.Recipient(), d)
+	})
+}
+
+/*
+	Closes the menu that a callback was made from and removes the dialog
+	It is meant to be called from endpoints, e.g. by a "Close" button
+*/
+func (e *Node) Close(c *tb.Callback) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return nil
+	}
+	id := c.Sender.Recipient()
+	if c.Message != nil {
+		id = e.flow.userKey(c.Message.Chat, c.Sender)
+	}
+	return e.flow.close(id, d)
+}
+
+/*
+	Deletes the menu message of a dialog or strips its keyboard and calls the close hook
+*/
+func (f *Menu) close(id string, d *Dialog) error {
+	f.dropDialog(id, d)
+	var err error
+	if f.keepOnClose || f.reply {
+		if f.closeCaption != "" {
+			d.setText(f.closeCaption)
+		}
+		_, err = f.edit(d, nil)
+	} else {
+		err = f.bot.Delete(d.Message)
+	}
+	if f.onClose != nil {
+		f.onClose(d.Position, d)
+	}
+	return err
+}
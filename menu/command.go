@@ -0,0 +1,46 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Registers a handler of a command (e.g. "/settings") on a bot that opens the menu at a node,
+	the root is opened if the node is nil
+	A user that has a dialog already gets the menu anew below the command with the values and the language
+	of the dialog kept (or resumed, see WithResume), other users get it in their language the same way as for a deep link
+	The bot is usually the bot of the flow
+	Returns the current menu
+*/
+func (f *Menu) BindCommand(bot Bot, command string, node *Node) *Menu {
+	if node == nil {
+		node = f.root
+	}
+	bot.Handle(command, func(m *tb.Message) {
+		if m == nil || m.Sender == nil || f.gate.closed() {
+			return
+		}
+		if err := f.command(m, node); err != nil {
+			f.fail(err, nil, node)
+		}
+	})
+	return f
+}
+
+/*
+	Opens the menu at a node for the sender of a command
+*/
+func (f *Menu) command(m *tb.Message, node *Node) error {
+	if !node.HasPage() && node.prev != nil {
+		node = node.prev
+	}
+	d, ok := f.DialogOfMessage(m)
+	if !ok {
+		return f.open(m.Sender, node)
+	}
+	if resumed, err := f.resumeDialog(m, d, node); resumed {
+		return err
+	}
+	lang := f.resolve(d.Language)
+	return f.StartAt(m.Sender, f.engine.Lang(lang).Tr(node.path), lang, node)
+}
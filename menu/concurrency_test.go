@@ -0,0 +1,242 @@
+package menu_test
+
+import (
+	"fmt"
+	"go-telegram-flow/menu"
+	"go-telegram-flow/menu/menutest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const concurrentUsers = 200
+
+func TestConcurrentTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	list := addList(flow, 5, 2)
+	flow.Build("en")
+	var wg sync.WaitGroup
+	for i := 1; i <= concurrentUsers; i++ {
+		wg.Add(1)
+		go func(user *tb.User) {
+			defer wg.Done()
+			if err := flow.Start(user, "caption", "en"); err != nil {
+				t.Error(err)
+				return
+			}
+			taps := []func() error{
+				func() error { return bot.Tap(flow, user, "list") },
+				func() error { return bot.Press(user, "»") },
+				func() error { return bot.Tap(flow, user, "list/item3") },
+				func() error { return bot.Press(user, "«") },
+				func() error { return bot.Tap(flow, user, "list/item0") },
+			}
+			for _, tap := range taps {
+				if err := tap(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(&tb.User{ID: i})
+	}
+	wg.Wait()
+	if answers := len(bot.Responses); answers != concurrentUsers*5 {
+		t.Fatalf("%d callbacks get %d answers", concurrentUsers*5, answers)
+	}
+	for i := 1; i <= concurrentUsers; i++ {
+		if d, ok := flow.GetDialog(strconv.Itoa(i)); !ok || d.Position != list {
+			t.Fatalf("the dialog of user %d is not left at the list", i)
+		}
+	}
+}
+
+/*
+	Starts the menus of the users at the list and taps them from two goroutines per user,
+	while a background job runs until the taps are done
+	Every callback has to get a single answer and every menu has to stay at the list
+*/
+func tapConcurrently(t *testing.T, flow *menu.Menu, bot *menutest.Bot, background func()) {
+	t.Helper()
+	users := make([]*tb.User, concurrentUsers)
+	for i := range users {
+		users[i] = &tb.User{ID: i + 1}
+		if err := flow.StartAt(users[i], "caption", "en", flow.Find("list")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				background()
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	var callbacks int64
+	for _, user := range users {
+		for _, path := range []string{"list/item0", "list/item1"} {
+			wg.Add(1)
+			go func(user *tb.User, path string) {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					// the other goroutine may have turned the page
+					taps := []func() error{
+						func() error { return bot.Tap(flow, user, path) },
+						func() error { return bot.Press(user, "»") },
+						func() error { return bot.Press(user, "«") },
+					}
+					for _, tap := range taps {
+						switch err := tap(); err {
+						case nil:
+							atomic.AddInt64(&callbacks, 1)
+						case menutest.ErrButtonNotFound:
+						default:
+							t.Error(err)
+						}
+					}
+				}
+			}(user, path)
+		}
+	}
+	wg.Wait()
+	close(done)
+	<-stopped
+	if answers := int64(len(bot.Responses)); answers != callbacks {
+		t.Fatalf("%d callbacks get %d answers", callbacks, answers)
+	}
+	var items []*menu.Node
+	for i := 0; i < 5; i++ {
+		items = append(items, flow.Find(fmt.Sprint("list/item", i)))
+	}
+	for _, user := range users {
+		if !showsAny(bot.Last(user), items) {
+			t.Fatalf("the menu of user %d is not left at the list", user.ID)
+		}
+	}
+}
+
+/*
+	Checks if a message displays a button of any of the nodes
+*/
+func showsAny(msg *tb.Message, nodes []*menu.Node) bool {
+	if msg == nil {
+		return false
+	}
+	for _, row := range msg.ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			for _, node := range nodes {
+				if node.IsButton(btn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestBroadcastDuringTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	addList(flow, 5, 2)
+	flow.Build("en")
+	tapConcurrently(t, flow, bot, func() {
+		flow.BroadcastRefresh(nil)
+	})
+}
+
+func TestAutoRefreshDuringTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	list := addList(flow, 5, 2)
+	list.AutoRefresh(time.Millisecond, func(e *menu.Node, d *menu.Dialog) {
+		d.SetCaption(e, "refreshed")
+	})
+	flow.Build("en")
+	tapConcurrently(t, flow, bot, func() {
+		time.Sleep(time.Millisecond)
+	})
+}
+
+func TestProcessDuringTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	var mx sync.Mutex
+	handled := 0
+	flow.GetRoot().Add("name", nil)
+	flow.Find("name").AwaitText("your name", func(e *menu.Node, m *tb.Message) int {
+		mx.Lock()
+		handled++
+		mx.Unlock()
+		return menu.Forward
+	})
+	flow.Build("en")
+	users := make([]*tb.User, concurrentUsers)
+	for i := range users {
+		users[i] = &tb.User{ID: i + 1}
+		if err := flow.Start(users[i], "caption", "en"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	consumed := 0
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(2)
+		go func(user *tb.User) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				if err := bot.Tap(flow, user, "name"); err != nil {
+					t.Error(err)
+				}
+			}
+		}(user)
+		go func(user *tb.User) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				if flow.Process(&tb.Message{Sender: user, Chat: &tb.Chat{ID: int64(user.ID)}, Text: "Ann"}) {
+					mx.Lock()
+					consumed++
+					mx.Unlock()
+				}
+			}
+		}(user)
+	}
+	wg.Wait()
+	if handled != consumed {
+		t.Fatalf("%d texts are consumed, but %d are handled", consumed, handled)
+	}
+}
+
+func TestShortcutsDuringTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	flow.WithNumbering()
+	addList(flow, 5, 2)
+	flow.Build("en")
+	tapConcurrently(t, flow, bot, func() {
+		for user := 1; user <= concurrentUsers; user++ {
+			sender := &tb.User{ID: user}
+			flow.Process(&tb.Message{Sender: sender, Chat: &tb.Chat{ID: int64(user)}, Text: "1"})
+		}
+	})
+}
+
+func TestMutatorsDuringTaps(t *testing.T) {
+	flow, bot := newFlow(t, "test")
+	list := addList(flow, 5, 2)
+	flow.Build("en")
+	tapConcurrently(t, flow, bot, func() {
+		for user := 1; user <= concurrentUsers; user++ {
+			sender := &tb.User{ID: user}
+			flow.SetCaption(sender, "caption %d", user)
+			if err := flow.MoveTo(sender, "caption", "en", list); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	confirmYes = "yes"
+	confirmNo  = "no"
+)
+
+/*
+	Adds a new node with a standard Yes/No page to the current node
+	Labels are shared by all confirmations and are localized by "<flow id>/yes" and "<flow id>/no" keys
+	After either choice the callback is called and the user is taken back to the current node's page,
+	results of the callbacks are ignored, nil callbacks are allowed
+	Returns the current node
+*/
+func (e *Node) AddConfirm(text string, onYes, onNo Callback) *Node {
+	confirm := e.AddSub(text, nil)
+	confirm.AddSub(confirmYes, confirmed(onYes)).labelKey = confirmYes
+	confirm.AddSub(confirmNo, confirmed(onNo)).labelKey = confirmNo
+	return e
+}
+
+/*
+	Wraps a confirmation callback, so it always leads back
+*/
+func confirmed(endpoint Callback) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		if endpoint != nil {
+			endpoint(e, c)
+		}
+		return Back
+	}
+}
@@ -0,0 +1,130 @@
+package menu
+
+import (
+	"context"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"time"
+)
+
+/*
+	Callback function declaration for endpoints that do blocking work (e.g. database or API calls)
+	The context carries the dialog and values of the flow's context,
+	it is cancelled when the endpoint times out, the dialog is closed or the flow's context is done
+*/
+type ContextCallback func(ctx context.Context, e *Node, c *tb.Callback) int
+
+type dialogKey struct{}
+
+/*
+	Converts a context aware endpoint to a regular one, so it can be passed to Add, AddSub and others
+*/
+func Contextual(endpoint ContextCallback) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		ctx, cancel := e.flow.context(c)
+		defer cancel()
+		return endpoint(ctx, e, c)
+	}
+}
+
+/*
+	Sets a context that the contexts of endpoints are derived from
+	Values of the context are available to every endpoint, cancelling it cancels all running endpoints
+*/
+func (f *Menu) WithContext(ctx context.Context) *Menu {
+	f.mx.Lock()
+	f.ctx = ctx
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Sets a time limit for context aware endpoints
+	Zero timeout means no limit
+*/
+func (f *Menu) WithEndpointTimeout(timeout time.Duration) *Menu {
+	f.mx.Lock()
+	f.endpointTimeout = timeout
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Gets the dialog that an endpoint is called in
+	Returns false if the context does not belong to an endpoint
+*/
+func DialogFromContext(ctx context.Context) (*Dialog, bool) {
+	d, ok := ctx.Value(dialogKey{}).(*Dialog)
+	return d, ok
+}
+
+/*
+	Creates a context for an endpoint that is called with a callback
+*/
+func (f *Menu) context(c *tb.Callback) (context.Context, context.CancelFunc) {
+	f.mx.RLock()
+	ctx, timeout := f.ctx, f.endpointTimeout
+	f.mx.RUnlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	d, ok := f.dialog(c)
+	if !ok {
+		return ctx, cancel
+	}
+	ctx = context.WithValue(ctx, dialogKey{}, d)
+	closed := d.done()
+	go func() {
+		select {
+		case <-closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+/*
+	Gets a channel that is closed when the dialog is closed
+*/
+func (d *Dialog) done() <-chan struct{} {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	if d.closed == nil {
+		d.closed = make(chan struct{})
+		if d.finished {
+			close(d.closed)
+		}
+	}
+	return d.closed
+}
+
+/*
+	Checks if the dialog has been closed
+*/
+func (d *Dialog) isClosed() bool {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.finished
+}
+
+/*
+	Closes the dialog, so that contexts of its running endpoints are cancelled
+	Only internal use is intended
+*/
+func (d *Dialog) close() {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	if d.finished {
+		return
+	}
+	d.finished = true
+	if d.closed != nil {
+		close(d.closed)
+	}
+}
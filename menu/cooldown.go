@@ -0,0 +1,126 @@
+package menu
+
+import (
+	"fmt"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	cooldownKey   = "cooldown"
+	cooldownAlert = "Please try again in %s"
+)
+
+/*
+	Sets a minimum interval between calls of the node's endpoint by the same user (e.g. "Request payout")
+	Presses made earlier are answered with an alert that shows the remaining time,
+	the alert is localized by "<flow id>/cooldown" and must contain a single %s verb for the time
+	The interval is kept by the flow, so starting a new menu does not reset it, unlike closing the dialog of the user
+	Returns the current node
+*/
+func (e *Node) SetCooldown(d time.Duration) *Node {
+	e.cooldown = d
+	return e
+}
+
+/*
+	An interval after which expired cooldowns of all users are removed
+*/
+const cooldownSweep = time.Minute
+
+/*
+	Ends of cooldowns of endpoints by users and node ids
+*/
+type cooldowns struct {
+	until map[int]map[string]time.Time
+	swept time.Time
+	mx    sync.Mutex
+}
+
+/*
+	Records a call of the node's endpoint by a user unless the cooldown of the node has not passed yet
+	Expired cooldowns of the user are removed, the ones of other users are removed once in a while
+	Returns the remaining time of the cooldown or zero if the call is allowed
+*/
+func (cd *cooldowns) take(e *Node, userID int, now time.Time) time.Duration {
+	cd.mx.Lock()
+	defer cd.mx.Unlock()
+	if cd.until == nil {
+		cd.until = make(map[int]map[string]time.Time)
+	}
+	if now.Sub(cd.swept) >= cooldownSweep {
+		for id := range cd.until {
+			cd.expire(id, now)
+		}
+		cd.swept = now
+	} else {
+		cd.expire(userID, now)
+	}
+	if until, ok := cd.until[userID][e.id]; ok {
+		return until.Sub(now)
+	}
+	if cd.until[userID] == nil {
+		cd.until[userID] = make(map[string]time.Time)
+	}
+	cd.until[userID][e.id] = now.Add(e.cooldown)
+	return 0
+}
+
+/*
+	Removes cooldowns of a user that have passed
+*/
+func (cd *cooldowns) expire(userID int, now time.Time) {
+	for id, until := range cd.until[userID] {
+		if !now.Before(until) {
+			delete(cd.until[userID], id)
+		}
+	}
+	if len(cd.until[userID]) == 0 {
+		delete(cd.until, userID)
+	}
+}
+
+/*
+	Removes all cooldowns of a user (e.g. when the dialog of the user is dropped)
+*/
+func (cd *cooldowns) drop(owner string) {
+	userID, err := strconv.Atoi(owner)
+	if err != nil {
+		return
+	}
+	cd.mx.Lock()
+	delete(cd.until, userID)
+	cd.mx.Unlock()
+}
+
+/*
+	Checks the cooldown of the node and alerts the user if it has not passed yet
+	Returns false if the endpoint may be called
+*/
+func (e *Node) cooling(c *tb.Callback, d *Dialog) bool {
+	if e.cooldown <= 0 || c.Sender == nil {
+		return false
+	}
+	remaining := e.flow.cooldowns.take(e, c.Sender.ID, time.Now())
+	if remaining <= 0 {
+		return false
+	}
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	text := fmt.Sprintf(e.flow.translate(d.Language, cooldownKey, cooldownAlert), remaining.Round(time.Second))
+	var err error
+	if c.ID == "" {
+		// reply keyboard presses can not be answered with an alert
+		e.flow.pace(c.Sender)
+		_, err = e.flow.bot.Send(c.Sender, text)
+	} else {
+		err = e.flow.respond(c, &tb.CallbackResponse{Text: text, ShowAlert: true})
+	}
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestCooldownEndsWithDialog(t *testing.T) {
+	flow, bot := newFlow(t, "cooldown")
+	calls := 0
+	flow.GetRoot().Add("payout", func(e *menu.Node, c *tb.Callback) int {
+		calls++
+		return menu.Stay
+	})
+	flow.Find("payout").SetCooldown(time.Hour)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	for i := 0; i < 2; i++ {
+		if err := flow.StartAt(user, "cooldown", "en", flow.GetRoot()); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			if err := bot.Tap(flow, user, "payout"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := flow.Close(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("the endpoint is called %d times instead of once per dialog", calls)
+	}
+}
@@ -0,0 +1,42 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"time"
+)
+
+/*
+	Makes repeated taps on the same button of a dialog within a window be ignored,
+	so an endpoint runs once per a sequence of taps (e.g. a double tap)
+	Every ignored tap prolongs the window
+	Zero window disables debouncing, though a callback delivered twice is ignored anyway
+*/
+func (f *Menu) WithDebounce(window time.Duration) *Menu {
+	f.debounce = window
+	return f
+}
+
+/*
+	The last tap on a button of a dialog
+*/
+type tap struct {
+	id     string
+	button string
+	at     time.Time
+}
+
+/*
+	Checks if a callback repeats the last tap of the dialog and has to be ignored
+	Only internal use is intended
+*/
+func (d *Dialog) repeated(c *tb.Callback, button string, window time.Duration) bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	now := time.Now()
+	if c.ID != "" && c.ID == d.tap.id {
+		return true
+	}
+	repeated := window > 0 && d.tap.button == button && now.Sub(d.tap.at) < window
+	d.tap = tap{id: c.ID, button: button, at: now}
+	return repeated
+}
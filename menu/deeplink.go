@@ -0,0 +1,66 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+const deepLinkSeparator = "__"
+
+/*
+	Takes over the /start command of the bot, so links like t.me/<bot>?start=settings__billing
+	open the menu at the node with the path "settings/billing"
+	Messages without a payload or with an unknown one are passed to the fallback, nil fallbacks are allowed
+*/
+func (f *Menu) HandleDeepLink(fallback func(m *tb.Message)) *Menu {
+	f.bot.Handle("/start", func(m *tb.Message) {
+		if !f.OpenDeepLink(m) && fallback != nil {
+			fallback(m)
+		}
+	})
+	return f
+}
+
+/*
+	Opens the menu at the node that the payload of a /start message points to
+	The menu is displayed in the language of the user if the flow has it,
+	the caption is localized by the path of the node the same way as for SendTo
+	Returns false if the payload does not point to a node
+*/
+func (f *Menu) OpenDeepLink(m *tb.Message) bool {
+	if m == nil || m.Sender == nil || m.Payload == "" {
+		return false
+	}
+	node := f.Find(strings.Replace(m.Payload, deepLinkSeparator, "/", -1))
+	if node == nil {
+		return false
+	}
+	if err := f.open(m.Sender, node); err != nil {
+		f.fail(err, nil, node)
+	}
+	return true
+}
+
+/*
+	Starts the menu for a user at the node's page in the language of the user
+	A node without a page is opened at the page of its parent
+*/
+func (f *Menu) open(user *tb.User, node *Node) error {
+	if !node.HasPage() && node.prev != nil {
+		node = node.prev
+	}
+	lang := f.languageOf(user)
+	return f.StartAt(user, f.engine.Lang(lang).Tr(node.path), lang, node)
+}
+
+/*
+	Gets a payload of a deep link that opens the node
+	Texts of the nodes must only contain letters, digits and underscores to fit in a link
+*/
+func (e *Node) DeepLink() string {
+	texts := make([]string, 0)
+	for node := e; node != nil && node.prev != nil; node = node.prev {
+		texts = append([]string{node.text}, texts...)
+	}
+	return strings.Join(texts, deepLinkSeparator)
+}
@@ -0,0 +1,226 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	A dialog is an abstract piece that holds a menu message sent by the bot
+	and a language that the interface is displayed
+	along with values stored by the nodes during the dialog
+*/
+type Dialog struct {
+	Message    *tb.Message
+	Language   string
+	Position   *Node
+	owner      string
+	history    []*Node
+	pages      map[string]int
+	text       string
+	textKey    string
+	captions   map[string]string
+	media      tb.InputMedia
+	input      *Node
+	saved      string
+	state      map[string]interface{}
+	active     int64
+	disabled   map[string]bool
+	dirty      bool
+	pending    map[*Node]bool
+	tap        tap
+	variant    string
+	progress   string
+	refreshing *Node
+	prompt     *tb.Message
+	undo       []step
+	redo       []step
+	undone     bool
+	used       map[string]int64
+	resumeFrom *step
+	restartAt  *Node
+	closed     chan struct{}
+	finished   bool
+	turn       turn
+	edits      uint64
+	mx         sync.RWMutex
+}
+
+/*
+	Gets the menu message of the dialog, it is nil until the menu is sent
+*/
+func (d *Dialog) GetMessage() *tb.Message {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.Message
+}
+
+/*
+	Gets a caption of the menu without any decorations
+*/
+func (d *Dialog) GetCaption() string {
+	if d.textKey != "" && d.Position != nil {
+		f := d.Position.flow
+		return f.engine.Lang(f.resolve(d.Language)).Tr(d.textKey)
+	}
+	return d.text
+}
+
+/*
+	Sets a caption of the menu replacing a localized one
+	Returns false if the caption is the same
+	Only internal use is intended
+*/
+func (d *Dialog) setText(text string) bool {
+	if d.text == text && d.textKey == "" {
+		return false
+	}
+	d.text = text
+	d.textKey = ""
+	return true
+}
+
+/*
+	Gets a page of the node's children that the dialog is currently on
+*/
+func (d *Dialog) Page(of *Node) int {
+	return d.pages[of.id]
+}
+
+/*
+	Sets a page of the node's children for the dialog
+	Only internal use is intended
+*/
+func (d *Dialog) setPage(of *Node, page int) {
+	if d.pages == nil {
+		d.pages = make(map[string]int)
+	}
+	d.pages[of.id] = page
+}
+
+/*
+	Gets the time of the last activity in the dialog
+*/
+func (d *Dialog) LastActive() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&d.active))
+}
+
+/*
+	Marks the dialog as active at the moment
+	Only internal use is intended
+*/
+func (d *Dialog) touch() {
+	atomic.StoreInt64(&d.active, time.Now().UnixNano())
+}
+
+/*
+	Checks if the node's button is enabled in the dialog
+*/
+func (d *Dialog) IsEnabled(e *Node) bool {
+	d.mx.RLock()
+	disabled := d.disabled[e.id]
+	d.mx.RUnlock()
+	return !disabled
+}
+
+/*
+	Enables or disables the node's button in the dialog and marks the dialog for an update
+	Only internal use is intended
+*/
+func (d *Dialog) setEnabled(e *Node, enabled bool) {
+	d.mx.Lock()
+	if enabled {
+		delete(d.disabled, e.id)
+	} else {
+		if d.disabled == nil {
+			d.disabled = make(map[string]bool)
+		}
+		d.disabled[e.id] = true
+	}
+	d.dirty = true
+	d.mx.Unlock()
+}
+
+/*
+	Marks the dialog as changed or up to date
+	Only internal use is intended
+*/
+func (d *Dialog) setDirty(dirty bool) {
+	d.mx.Lock()
+	d.dirty = dirty
+	d.mx.Unlock()
+}
+
+/*
+	Marks the node's page for an update in the dialog only, so changes made for one user
+	(e.g. a new caption) do not make menus of other users redrawn
+	Only internal use is intended
+*/
+func (d *Dialog) markPending(e *Node) {
+	d.mx.Lock()
+	if d.pending == nil {
+		d.pending = make(map[*Node]bool)
+	}
+	d.pending[e] = true
+	d.mx.Unlock()
+}
+
+/*
+	Checks if the dialog has changes that are not displayed yet or the node's page was marked for an update
+*/
+func (d *Dialog) mustUpdate(e *Node) bool {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.dirty || d.pending[e]
+}
+
+/*
+	Marks the dialog as up to date after its menu was updated
+*/
+func (d *Dialog) displayed() {
+	d.mx.Lock()
+	d.dirty = false
+	d.pending = nil
+	d.mx.Unlock()
+}
+
+/*
+	Marks the dialog for an update, so the menu is redrawn after the current callback
+	even if the pressed node has no page of its own
+*/
+func (d *Dialog) Refresh() {
+	d.setDirty(true)
+}
+
+/*
+	Stores a value in the dialog, so it can be used later by other nodes
+*/
+func (d *Dialog) Set(key string, value interface{}) {
+	d.mx.Lock()
+	if d.state == nil {
+		d.state = make(map[string]interface{})
+	}
+	d.state[key] = value
+	d.mx.Unlock()
+}
+
+/*
+	Retrieves a value stored in the dialog
+*/
+func (d *Dialog) Get(key string) (interface{}, bool) {
+	d.mx.RLock()
+	value, ok := d.state[key]
+	d.mx.RUnlock()
+	return value, ok
+}
+
+/*
+	Deletes a value stored in the dialog
+*/
+func (d *Dialog) Delete(key string) {
+	d.mx.Lock()
+	delete(d.state, key)
+	d.mx.Unlock()
+}
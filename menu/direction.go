@@ -0,0 +1,84 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const rtlBreadcrumbSeparator = " ‹ "
+
+/*
+	Layout hints of a locale
+	Right-to-left locales get mirrored rows of buttons and reversed breadcrumb separators
+*/
+type LocaleMeta struct {
+	RightToLeft bool
+}
+
+/*
+	Languages that are written from right to left unless their metadata says otherwise
+*/
+var rightToLeft = map[string]bool{
+	"ar": true,
+	"fa": true,
+	"he": true,
+}
+
+/*
+	Sets layout hints of a locale, the hints of a base locale (e.g. "ar") apply to its variants (e.g. "ar-EG")
+	Arabic, Farsi and Hebrew are right-to-left by default
+	Must be called before the flow is built
+*/
+func (f *Menu) SetLocaleMeta(lang string, meta LocaleMeta) *Menu {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.localeMeta == nil {
+		f.localeMeta = make(map[string]LocaleMeta)
+	}
+	f.localeMeta[lang] = meta
+	return f
+}
+
+/*
+	Gets layout hints of a locale
+*/
+func (f *Menu) GetLocaleMeta(lang string) LocaleMeta {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	for candidate := lang; candidate != ""; candidate = baseLocale(candidate) {
+		if meta, ok := f.localeMeta[candidate]; ok {
+			return meta
+		}
+		if rightToLeft[candidate] {
+			return LocaleMeta{RightToLeft: true}
+		}
+	}
+	return LocaleMeta{}
+}
+
+/*
+	Mirrors rows of buttons for a right-to-left locale, so the first button of a row is on the right
+	The rows are copied, since markups share buttons with the node's children
+*/
+func (f *Menu) direct(lang string, rows [][]tb.InlineButton) [][]tb.InlineButton {
+	if !f.GetLocaleMeta(lang).RightToLeft {
+		return rows
+	}
+	mirrored := make([][]tb.InlineButton, len(rows))
+	for i, row := range rows {
+		mirrored[i] = make([]tb.InlineButton, len(row))
+		for j, btn := range row {
+			mirrored[i][len(row)-1-j] = btn
+		}
+	}
+	return mirrored
+}
+
+/*
+	Gets a separator of breadcrumbs for a locale
+*/
+func (f *Menu) separator(lang string) string {
+	if f.GetLocaleMeta(lang).RightToLeft {
+		return rtlBreadcrumbSeparator
+	}
+	return breadcrumbSeparator
+}
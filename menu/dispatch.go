@@ -0,0 +1,72 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+const routeSeparator = "|"
+
+/*
+	Registers a handler for callbacks of a route, which is an id of a node or of its auxiliary button
+	Every button of the flow is sent with the id of the flow as its unique and the route in its data,
+	so the bot gets a single handler that dispatches callbacks instead of a handler per button and locale
+	Only internal use is intended
+*/
+func (f *Menu) route(route string, handler func(c *tb.Callback)) {
+	f.mx.Lock()
+	if f.routes == nil {
+		f.routes = make(map[string]func(c *tb.Callback))
+	}
+	f.routes[route] = handler
+	first := !f.dispatching
+	f.dispatching = true
+	f.mx.Unlock()
+	if first {
+		f.bot.Handle(&tb.InlineButton{Unique: f.id}, f.dispatch)
+	}
+}
+
+/*
+	Handler of the flow's buttons that passes a callback to the handler of its route
+	The callback gets the data of the button without the route
+	Callbacks of unknown routes (e.g. of nodes removed after a restart) are treated as stale
+*/
+func (f *Menu) dispatch(c *tb.Callback) {
+	route, data := c.Data, ""
+	if i := strings.Index(c.Data, routeSeparator); i >= 0 {
+		route, data = c.Data[:i], c.Data[i+1:]
+	}
+	f.mx.RLock()
+	handler, ok := f.routes[route]
+	f.mx.RUnlock()
+	if !ok {
+		f.bot.Respond(c)
+		f.stale(c)
+		return
+	}
+	c.Data = data
+	if d, ok := f.dialog(c); ok {
+		// taps of a dialog are handled one at a time along with its background updates
+		d.serialize(func() { handler(c) })
+		return
+	}
+	handler(c)
+}
+
+/*
+	Joins a route with data of a button
+*/
+func routeData(route, data string) string {
+	if data == "" {
+		return route
+	}
+	return route + routeSeparator + data
+}
+
+/*
+	Checks if an inline button is the node's button (e.g. to find it in a markup of a sent message)
+*/
+func (e *Node) IsButton(btn tb.InlineButton) bool {
+	return btn.Unique == e.flow.id && (btn.Data == e.id || strings.HasPrefix(btn.Data, e.id+routeSeparator))
+}
@@ -0,0 +1,300 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+)
+
+/*
+	An admin section that edits the tree of another menu from Telegram itself
+	Authorized users can add, rename, reorder, hide and remove nodes of the target menu,
+	the changes are applied right away and passed to a callback as a definition of the tree,
+	so they can be saved and loaded with Menu.Load on the next start
+	Labels of the editor's buttons are localized by "<flow id>/editor_*" keys of the menu it is attached to
+*/
+type Editor struct {
+	target    *Menu
+	node      *Node
+	key       string
+	authorize func(userID int) bool
+	onChange  func(root *Definition)
+}
+
+/*
+	Creates an editor of a target menu, it has to be attached to a node of an admin menu (see Attach)
+	Nobody may edit the menu until an authorizer is set
+*/
+func NewEditor(target *Menu) *Editor {
+	return &Editor{target: target}
+}
+
+/*
+	Sets a function that decides whether a user may edit the target menu
+	Returns the editor
+*/
+func (ed *Editor) WithAuthorizer(authorize func(userID int) bool) *Editor {
+	ed.authorize = authorize
+	return ed
+}
+
+/*
+	Sets a callback that gets the definition of the target tree after every change
+	Returns the editor
+*/
+func (ed *Editor) OnChange(persist func(root *Definition)) *Editor {
+	ed.onChange = persist
+	return ed
+}
+
+/*
+	Adds a new node that opens the editor to a parent node
+	Must be called before the flow is built
+	Returns the new node
+*/
+func (ed *Editor) Attach(parent *Node, text string) *Node {
+	ed.node = parent.AddSub(text, ed.open).
+		SetProvider(NodeProviderFunc(ed.nodes)).
+		SetGuard(ed.guard)
+	ed.key = "editor_" + ed.node.GetId()
+	return ed.node
+}
+
+/*
+	Get the node that opens the editor
+*/
+func (ed *Editor) GetNode() *Node {
+	return ed.node
+}
+
+/*
+	Endpoint of the editor's node that starts editing from the root of the target
+*/
+func (ed *Editor) open(e *Node, c *tb.Callback) int {
+	if d, ok := e.flow.dialog(c); ok {
+		ed.selectPath(d, "")
+	}
+	return Forward
+}
+
+/*
+	Lets only authorized users edit the target
+*/
+func (ed *Editor) guard(e *Node, c *tb.Callback) bool {
+	return ed.allowed(c.Sender)
+}
+
+/*
+	Checks if a user may edit the target
+*/
+func (ed *Editor) allowed(user *tb.User) bool {
+	return user != nil && ed.authorize != nil && ed.authorize(user.ID)
+}
+
+/*
+	Generates buttons of the children of the selected node followed by the editing actions
+*/
+func (ed *Editor) nodes(e *Node, d *Dialog) []*Node {
+	f := e.flow
+	selected := ed.selected(d)
+	lang := ed.target.resolve(d.Language)
+	hidden := f.translate(d.Language, "editor_hidden", "🙈")
+	ed.target.tree.RLock()
+	children := append([]*Node(nil), selected.nodes...)
+	labels := make([]string, len(children))
+	for i, child := range children {
+		labels[i] = child.label(lang)
+		if child.hidden {
+			labels[i] = hidden + " " + labels[i]
+		}
+		if child.HasPage() {
+			labels[i] += " ›"
+		}
+	}
+	ed.target.tree.RUnlock()
+	nodes := make([]*Node, 0, len(children)+7)
+	for i, child := range children {
+		nodes = append(nodes, ed.action(f.NewNode(labels[i], ed.pick(child.relativePath()))))
+	}
+	nodes = append(nodes, ed.action(f.NewNode(f.translate(d.Language, "editor_add", "➕ Add a button"), nil).
+		AwaitText(f.translate(d.Language, "editor_add_prompt", "Type a label of the new button"), ed.add)))
+	if selected == ed.target.root {
+		return nodes
+	}
+	toggle := f.translate(d.Language, "editor_hide", "🙈 Hide")
+	if selected.hidden {
+		toggle = f.translate(d.Language, "editor_show", "👁 Show")
+	}
+	return append(nodes,
+		ed.action(f.NewNode(f.translate(d.Language, "editor_rename", "✏ Rename"), nil).
+			AwaitText(f.translate(d.Language, "editor_rename_prompt", "Type a new label of the button"), ed.rename)),
+		ed.action(f.NewNode(f.translate(d.Language, "editor_up", "⬆ Move up"), ed.move(-1))),
+		ed.action(f.NewNode(f.translate(d.Language, "editor_down", "⬇ Move down"), ed.move(1))),
+		ed.action(f.NewNode(toggle, ed.toggle)),
+		ed.action(f.NewNode(f.translate(d.Language, "editor_remove", "🗑 Remove"), ed.remove)),
+		ed.action(f.NewNode(f.translate(d.Language, "editor_parent", "« Parent"), ed.pick(selected.prev.relativePath()))),
+	)
+}
+
+/*
+	Protects a provided button of the editor with the editor's authorizer
+*/
+func (ed *Editor) action(e *Node) *Node {
+	return e.SetGuard(ed.guard)
+}
+
+/*
+	Gets the node of the target that a dialog edits
+	The node is stored by its path, so a removed node selects the root
+*/
+func (ed *Editor) selected(d *Dialog) *Node {
+	path, _ := d.Get(ed.key)
+	text, _ := path.(string)
+	ed.target.tree.RLock()
+	defer ed.target.tree.RUnlock()
+	if node := ed.target.Find(text); node != nil {
+		return node
+	}
+	return ed.target.root
+}
+
+/*
+	Selects a node of the target by its path and displays the path in the caption
+*/
+func (ed *Editor) selectPath(d *Dialog, path string) {
+	d.Set(ed.key, path)
+	caption := ed.node.flow.translate(d.Language, "editor_caption", "Editing") + ": /" + path
+	d.SetCaption(ed.node, caption)
+}
+
+/*
+	Creates an endpoint that selects a node of the target
+*/
+func (ed *Editor) pick(path string) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		if d, ok := e.flow.dialog(c); ok {
+			ed.selectPath(d, path)
+			d.Refresh()
+		}
+		return Forward
+	}
+}
+
+/*
+	Adds a child with a typed label to the selected node
+*/
+func (ed *Editor) add(e *Node, m *tb.Message) int {
+	d, ok := e.flow.DialogOfMessage(m)
+	if !ok || !ed.allowed(m.Sender) || m.Text == "" {
+		return Forward
+	}
+	selected := ed.selected(d)
+	child := ed.target.NewNode(ed.freeText(selected), nil).SetLabel(m.Text)
+	selected.InsertAt(-1, child)
+	ed.changed()
+	return Forward
+}
+
+/*
+	Gets a text for a new child of a node that none of its children has
+*/
+func (ed *Editor) freeText(parent *Node) string {
+	ed.target.tree.RLock()
+	defer ed.target.tree.RUnlock()
+	texts := make(map[string]bool, len(parent.nodes))
+	for _, child := range parent.nodes {
+		texts[child.text] = true
+	}
+	for i := len(parent.nodes) + 1; ; i++ {
+		if text := "node" + strconv.Itoa(i); !texts[text] {
+			return text
+		}
+	}
+}
+
+/*
+	Replaces the label of the selected node with a typed one
+*/
+func (ed *Editor) rename(e *Node, m *tb.Message) int {
+	d, ok := e.flow.DialogOfMessage(m)
+	if !ok || !ed.allowed(m.Sender) || m.Text == "" {
+		return Forward
+	}
+	selected := ed.selected(d)
+	if selected == ed.target.root {
+		return Forward
+	}
+	ed.target.tree.Lock()
+	selected.SetLabel(m.Text)
+	ed.target.rebuild()
+	ed.target.tree.Unlock()
+	ed.changed()
+	return Forward
+}
+
+/*
+	Creates an endpoint that moves the selected node among its siblings
+*/
+func (ed *Editor) move(delta int) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		d, ok := e.flow.dialog(c)
+		if !ok {
+			return Stay
+		}
+		selected := ed.selected(d)
+		if selected != ed.target.root && selected.prev.move(selected, delta) {
+			ed.changed()
+			d.Refresh()
+		}
+		return Forward
+	}
+}
+
+/*
+	Endpoint that hides the selected node or displays it again
+*/
+func (ed *Editor) toggle(e *Node, c *tb.Callback) int {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return Stay
+	}
+	selected := ed.selected(d)
+	if selected == ed.target.root {
+		return Stay
+	}
+	ed.target.tree.Lock()
+	selected.SetHidden(!selected.hidden)
+	ed.target.rebuild()
+	ed.target.tree.Unlock()
+	ed.changed()
+	d.Refresh()
+	return Forward
+}
+
+/*
+	Endpoint that removes the selected node and selects its parent
+*/
+func (ed *Editor) remove(e *Node, c *tb.Callback) int {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return Stay
+	}
+	selected := ed.selected(d)
+	if selected == ed.target.root {
+		return Stay
+	}
+	parent := selected.prev
+	parent.Remove(selected)
+	ed.selectPath(d, parent.relativePath())
+	ed.changed()
+	d.Refresh()
+	return Forward
+}
+
+/*
+	Passes the definition of the changed target to the callback
+*/
+func (ed *Editor) changed() {
+	if ed.onChange != nil {
+		ed.onChange(ed.target.Definition())
+	}
+}
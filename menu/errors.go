@@ -0,0 +1,36 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A handler of failures that happen while a menu is handling updates
+	The callback is nil when the failure was caused by a text message or a background job
+*/
+type ErrorHandler func(err error, c *tb.Callback, node *Node)
+
+/*
+	Sets a handler for failures that happen while a menu is handling updates
+	The failures are written to the logger when no handler is set
+*/
+func (f *Menu) SetErrorHandler(handler ErrorHandler) *Menu {
+	f.onError = handler
+	return f
+}
+
+/*
+	Passes a failure to the error handler
+	Only internal use is intended
+*/
+func (f *Menu) fail(err error, c *tb.Callback, node *Node) {
+	if f.onError != nil {
+		f.onError(err, c, node)
+		return
+	}
+	recipient := ""
+	if c != nil && c.Sender != nil {
+		recipient = c.Sender.Recipient()
+	}
+	f.logger.Error("failed to continue", logFields(recipient, node, err))
+}
@@ -0,0 +1,72 @@
+package menu
+
+import (
+	"strconv"
+)
+
+/*
+	A receiver of exposures of users to variants of experiments
+	A MetricsSink that implements it gets an exposure every time a user enters the page of a variant
+*/
+type ExperimentSink interface {
+	Exposed(experiment, variant string, userID int)
+}
+
+/*
+	An A/B test that routes users to one of its variants
+*/
+type experiment struct {
+	name     string
+	splitter func(userID int) string
+}
+
+/*
+	Adds an experiment that routes every user to one of the subtrees by the name the splitter gives for the user
+	(e.g. a hash of the user id), so the same user always gets the same variant
+	The variant nodes have to be siblings in the tree (e.g. added with AddSub to the same parent),
+	only the button of the variant of the user is displayed, so they may share a label
+	Menus without an owner (see Dialog.GetOwner) display the variant that the splitter gives for zero
+	Returns the current menu
+*/
+func (f *Menu) WithExperiment(name string, variants map[string]*Node, splitter func(userID int) string) *Menu {
+	x := &experiment{name: name, splitter: splitter}
+	f.tree.Lock()
+	for variant, node := range variants {
+		node.experiment, node.variant = x, variant
+	}
+	f.tree.Unlock()
+	return f
+}
+
+/*
+	Gets a variant of the experiment that a dialog is routed to
+*/
+func (x *experiment) variantOf(d *Dialog) (string, int) {
+	id, _ := strconv.Atoi(d.owner)
+	return x.splitter(id), id
+}
+
+/*
+	Checks if the node is not a variant of an experiment or is the variant the dialog is routed to
+*/
+func (e *Node) inVariant(d *Dialog) bool {
+	if e.experiment == nil {
+		return true
+	}
+	variant, _ := e.experiment.variantOf(d)
+	return variant == e.variant
+}
+
+/*
+	Reports an exposure of the owner of a dialog to the variant of the node
+*/
+func (f *Menu) reportExposed(node *Node, d *Dialog) {
+	sink, ok := f.metrics.(ExperimentSink)
+	if !ok || node.experiment == nil {
+		return
+	}
+	variant, id := node.experiment.variantOf(d)
+	if variant == node.variant {
+		sink.Exposed(node.experiment.name, variant, id)
+	}
+}
@@ -0,0 +1,134 @@
+package menu
+
+import (
+	"time"
+)
+
+/*
+	What happens to the menu message of a dialog that has been idle for too long
+*/
+type IdleAction int
+
+const (
+	// the message is left as it is
+	IdleKeep IdleAction = iota
+	// the keyboard is removed
+	IdleStrip
+	// the keyboard is removed and the caption is replaced with a text localized by "<flow id>/expired"
+	IdleCollapse
+	// the message is deleted
+	IdleDelete
+)
+
+const (
+	expiredCaptionKey = "expired"
+	expiredCaption    = "This menu has expired"
+)
+
+/*
+	Sets a time after which idle dialogs are removed by a background sweeper
+	If strip is true the keyboard of an expired menu is removed as well
+	Zero duration disables the expiration
+*/
+func (f *Menu) SetDialogTTL(ttl time.Duration, strip bool) *Menu {
+	action := IdleKeep
+	if strip {
+		action = IdleStrip
+	}
+	return f.WithIdleTimeout(ttl, action)
+}
+
+/*
+	Sets a time after which idle dialogs are removed by a background sweeper
+	and an action that is applied to their menus, so stale menus do not stay in chats
+	Zero duration disables the expiration
+*/
+func (f *Menu) WithIdleTimeout(ttl time.Duration, action IdleAction) *Menu {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.ttl = ttl
+	f.idleAction = action
+	if f.sweeper != nil {
+		close(f.sweeper)
+		f.sweeper = nil
+	}
+	if ttl > 0 {
+		f.sweeper = make(chan struct{})
+		go f.sweep(ttl, f.sweeper)
+	}
+	return f
+}
+
+/*
+	Sets a callback that is called for every expired dialog
+*/
+func (f *Menu) OnExpire(handler func(d *Dialog)) *Menu {
+	f.mx.Lock()
+	f.onExpire = handler
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Periodically removes idle dialogs until stopped
+*/
+func (f *Menu) sweep(ttl time.Duration, stop chan struct{}) {
+	interval := ttl / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			f.expire(now.Add(-ttl))
+		}
+	}
+}
+
+/*
+	Removes all dialogs that were idle since a specified moment
+*/
+func (f *Menu) expire(since time.Time) {
+	expired := f.dialogs.remove(func(d *Dialog) bool {
+		return d.LastActive().Before(since)
+	})
+	f.mx.RLock()
+	action, handler := f.idleAction, f.onExpire
+	f.mx.RUnlock()
+	for _, d := range expired {
+		d := d
+		d.close()
+		// a tap that is being handled finishes before the menu is collapsed
+		d.serialize(func() {
+			if d.Message != nil {
+				if err := f.collapse(d, action); err != nil {
+					f.fail(err, nil, d.Position)
+				}
+			}
+			if handler != nil {
+				handler(d)
+			}
+		})
+	}
+}
+
+/*
+	Applies an idle action to the menu message of an expired dialog
+*/
+func (f *Menu) collapse(d *Dialog, action IdleAction) error {
+	var err error
+	switch action {
+	case IdleStrip:
+		_, err = f.edit(d, nil)
+	case IdleCollapse:
+		d.setText(f.translate(d.Language, expiredCaptionKey, expiredCaption))
+		_, err = f.edit(d, nil)
+	case IdleDelete:
+		err = f.bot.Delete(d.Message)
+	}
+	return err
+}
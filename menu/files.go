@@ -0,0 +1,117 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+const (
+	wrongFileKey = "wrong_file"
+	wrongFile    = "This file can not be accepted"
+	largeFileKey = "large_file"
+	largeFile    = "The file is too large"
+)
+
+/*
+	A kind of a file that a node awaits
+*/
+type FileKind string
+
+const (
+	PhotoFile    FileKind = "photo"
+	DocumentFile FileKind = "document"
+	VoiceFile    FileKind = "voice"
+	VideoFile    FileKind = "video"
+	AudioFile    FileKind = "audio"
+)
+
+/*
+	Makes the node await a file of one of the kinds from a user when pressed
+	Messages with files have to be passed to Menu.Process (e.g. from OnPhoto and OnDocument handlers),
+	files of other kinds are rejected with a message localized by "<flow id>/wrong_file",
+	text messages are left to other handlers, so pressing a button of the menu cancels the upload
+	The result of the handler is treated the same way as for AwaitText
+	Returns the current node
+*/
+func (e *Node) AwaitFile(kinds []FileKind, handler func(e *Node, m *tb.Message) int) *Node {
+	e.input = &textInput{handler: handler, kinds: kinds}
+	return e
+}
+
+/*
+	Limits files that the node awaits by a size in bytes and by MIME types (e.g. "application/pdf"),
+	zero size and no types mean no limit
+	Files that are too large are rejected with a message localized by "<flow id>/large_file"
+	Returns the current node
+*/
+func (e *Node) SetFileLimits(maxSize int, types ...string) *Node {
+	if e.input != nil {
+		e.input.maxSize, e.input.types = maxSize, types
+	}
+	return e
+}
+
+/*
+	Sets a prompt that replaces the caption while the node awaits a file, the caption stays by default
+	Returns the current node
+*/
+func (e *Node) SetFilePrompt(prompt string) *Node {
+	if e.input != nil {
+		e.input.prompt = prompt
+	}
+	return e
+}
+
+/*
+	Checks a file of a message against the awaited kinds and limits
+	Returns a locale key and a fallback of the rejection message or empty strings if the file is accepted
+	Text inputs accept any message
+*/
+func (in *textInput) reject(m *tb.Message) (string, string) {
+	if in.kinds == nil {
+		return "", ""
+	}
+	kind, file, mime := fileOf(m)
+	accepted := false
+	for _, k := range in.kinds {
+		accepted = accepted || k == kind
+	}
+	if !accepted || len(in.types) > 0 && !hasType(in.types, mime) {
+		return wrongFileKey, wrongFile
+	}
+	if in.maxSize > 0 && file.FileSize > in.maxSize {
+		return largeFileKey, largeFile
+	}
+	return "", ""
+}
+
+/*
+	Gets a kind, a file and a MIME type of a file attached to a message
+*/
+func fileOf(m *tb.Message) (FileKind, tb.File, string) {
+	switch {
+	case m.Photo != nil:
+		return PhotoFile, m.Photo.File, "image/jpeg"
+	case m.Document != nil:
+		return DocumentFile, m.Document.File, m.Document.MIME
+	case m.Voice != nil:
+		return VoiceFile, m.Voice.File, m.Voice.MIME
+	case m.Video != nil:
+		return VideoFile, m.Video.File, m.Video.MIME
+	case m.Audio != nil:
+		return AudioFile, m.Audio.File, m.Audio.MIME
+	}
+	return "", tb.File{}, ""
+}
+
+/*
+	Checks if a MIME type is one of the types, a type may end with "/*" to match a group (e.g. "image/*")
+*/
+func hasType(types []string, mime string) bool {
+	for _, t := range types {
+		if t == mime || strings.HasSuffix(t, "/*") && strings.HasPrefix(mime, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
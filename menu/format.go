@@ -0,0 +1,37 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Sets options that the menu message is sent and edited with while the dialog is on the node's page
+	(e.g. tb.ModeHTML as a parse mode or a disabled web page preview), so captions can be formatted
+	Breadcrumbs and progress bars become a part of the formatted text, their labels have to be escaped
+	The reply markup of the options is ignored, the page's markup is used instead
+	Returns the current node
+*/
+func (e *Node) SetSendOptions(options *tb.SendOptions) *Node {
+	e.sendOptions = options
+	return e
+}
+
+/*
+	Get options that the menu message is sent and edited with on the node's page
+*/
+func (e *Node) GetSendOptions() *tb.SendOptions {
+	return e.sendOptions
+}
+
+/*
+	Gets arguments of a send or an edit of the menu message on the node's page with a markup
+*/
+func (e *Node) options(markup *tb.ReplyMarkup, options ...interface{}) []interface{} {
+	if e == nil || e.sendOptions == nil {
+		return append([]interface{}{markup}, options...)
+	}
+	opts := *e.sendOptions
+	opts.ReplyMarkup = markup
+	// options go first, since telebot replaces everything before them
+	return append([]interface{}{&opts}, options...)
+}
@@ -0,0 +1,113 @@
+package menu
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown graph format")
+	mermaidEscaper   = strings.NewReplacer("\"", "#quot;", "\n", "<br/>")
+)
+
+/*
+	A format of an exported menu tree
+*/
+type GraphFormat int
+
+const (
+	GraphDOT GraphFormat = iota
+	GraphMermaid
+)
+
+/*
+	Writes the tree of the flow with paths and endpoint names as a Graphviz DOT or a Mermaid graph
+	Provided nodes are generated at display time, so only their parents are exported
+*/
+func (f *Menu) ExportGraph(w io.Writer, format GraphFormat) error {
+	var b strings.Builder
+	switch format {
+	case GraphDOT:
+		fmt.Fprintf(&b, "digraph %q {\n", f.id)
+		f.root.walk(func(e *Node) {
+			fmt.Fprintf(&b, "\t%q [label=%q];\n", e.id, e.graphLabel())
+			if e.prev != nil {
+				fmt.Fprintf(&b, "\t%q -> %q;\n", e.prev.id, e.id)
+			}
+		})
+		b.WriteString("}\n")
+	case GraphMermaid:
+		b.WriteString("graph TD\n")
+		f.root.walk(func(e *Node) {
+			fmt.Fprintf(&b, "\tn%s[\"%s\"]\n", graphId(e), mermaidEscaper.Replace(e.graphLabel()))
+			if e.prev != nil {
+				fmt.Fprintf(&b, "\tn%s --> n%s\n", graphId(e.prev), graphId(e))
+			}
+		})
+	default:
+		return ErrUnknownFormat
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+/*
+	Calls a function for the node and all of its static descendants, parents go first
+*/
+func (e *Node) walk(fn func(e *Node)) {
+	fn(e)
+	for _, child := range e.nodes {
+		child.walk(fn)
+	}
+}
+
+/*
+	Gets a label of the node in a graph: its path, the endpoint and special kinds of buttons
+*/
+func (e *Node) graphLabel() string {
+	label := e.path
+	if label == "" {
+		label = e.text
+	}
+	switch {
+	case e.url != "":
+		label += "\nurl: " + e.url
+	case e.query != "":
+		label += "\ninline: " + e.query
+	case e.input != nil:
+		label += "\ninput"
+	case e.endpoint != nil:
+		label += "\n" + funcName(e.endpoint)
+	}
+	if e.provider != nil {
+		label += "\nprovided"
+	}
+	return label
+}
+
+/*
+	Gets an identificator of the node that is safe to use in Mermaid
+*/
+func graphId(e *Node) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, e.id)
+}
+
+/*
+	Gets a name of a function for humans (e.g. "main.userPress")
+*/
+func funcName(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,52 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Sets a function that is checked before the node's endpoint is called (e.g. if a cart is not empty),
+	so preconditions are kept out of the endpoint
+	When the guard fails the dialog stays on its page and the user gets an alert (see SetGuardAlert)
+	Returns the current node
+*/
+func (e *Node) SetGuard(guard func(e *Node, c *tb.Callback) bool) *Node {
+	e.guard = guard
+	return e
+}
+
+/*
+	Sets a text of the alert that is displayed when the node's guard fails
+	The text is localized the same way as for Alert, the alert of disabled buttons is displayed by default
+	Returns the current node
+*/
+func (e *Node) SetGuardAlert(text string) *Node {
+	e.guardAlert = text
+	return e
+}
+
+/*
+	Checks the node's guard and alerts the user if it fails
+	Returns false if the endpoint may be called
+*/
+func (e *Node) guarded(c *tb.Callback, d *Dialog) bool {
+	if e.guard == nil || e.guard(e, c) {
+		return false
+	}
+	text := e.flow.disabledAlert
+	if e.guardAlert != "" {
+		text = e.flow.translate(d.Language, e.guardAlert, e.guardAlert)
+	}
+	var err error
+	if c.ID == "" {
+		// reply keyboard presses can not be answered with an alert
+		e.flow.pace(c.Sender)
+		_, err = e.flow.bot.Send(c.Sender, text)
+	} else {
+		err = e.flow.respond(c, &tb.CallbackResponse{Text: text, ShowAlert: true})
+	}
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const historyLimit = 32
+
+/*
+	Gets pages that the dialog has visited before the current one, the most recent is the last
+*/
+func (d *Dialog) History() []*Node {
+	return append([]*Node(nil), d.history...)
+}
+
+/*
+	Takes the user back by a specified number of visited pages
+	The root page is displayed if the history is shorter
+	An endpoint that calls it should return Stay, so the menu is not updated twice
+*/
+func (e *Node) BackN(c *tb.Callback, n int) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	if n < 1 {
+		return nil
+	}
+	d.cancelInput()
+	page := e.flow.root
+	if n <= len(d.history) {
+		page = d.history[len(d.history)-n]
+	}
+	return e.update(c.Sender, d, page)
+}
+
+/*
+	Gets the page that was displayed before the current one
+	Returns nil if the history is empty
+*/
+func (d *Dialog) previous() *Node {
+	if len(d.history) == 0 {
+		return nil
+	}
+	return d.history[len(d.history)-1]
+}
+
+/*
+	Records a move between pages
+	Returning to a visited page cuts the history down to the moment it was left,
+	so going back pops the history instead of growing it
+	Only internal use is intended
+*/
+func (d *Dialog) record(from, to *Node) {
+	if from == nil || from == to {
+		return
+	}
+	for i, page := range d.history {
+		if page == to {
+			d.history = d.history[:i]
+			return
+		}
+	}
+	if to == to.flow.root {
+		d.history = nil
+		return
+	}
+	d.history = append(d.history, from)
+	if len(d.history) > historyLimit {
+		d.history = d.history[len(d.history)-historyLimit:]
+	}
+}
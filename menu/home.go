@@ -0,0 +1,36 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Adds a home button that takes a user to the root page to every page deeper than the first level
+	The label is a locale key relative to the flow (e.g. "home" is localized by "<flow id>/home")
+	Must be called before the flow is built
+*/
+func (f *Menu) WithHomeButton(label string) *Menu {
+	f.homeKey = label
+	return f
+}
+
+/*
+	Takes the user straight to the root page
+	An endpoint that calls it should return Stay, so the menu is not updated twice
+*/
+func (e *Node) Home(c *tb.Callback) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	d.cancelInput()
+	return e.update(c.Sender, d, e.flow.root)
+}
+
+/*
+	Takes the dialog from the node's page to the root page
+*/
+func (e *Node) home(to tb.Recipient, d *Dialog) error {
+	d.cancelInput()
+	return e.update(to, d, e.flow.root)
+}
@@ -0,0 +1,68 @@
+package menu
+
+/*
+	A hook that is called when a dialog enters or leaves the page of a node
+*/
+type PageHook func(node *Node, d *Dialog)
+
+/*
+	A hook that is called every time a dialog is displayed at a page
+	The pages are the same when the page is only updated, from is nil for a new menu
+*/
+type NavigationHook func(from, to *Node, d *Dialog)
+
+/*
+	Sets a hook that is called after a dialog has entered a page
+*/
+func (f *Menu) OnEnter(hook PageHook) *Menu {
+	f.onEnter = hook
+	return f
+}
+
+/*
+	Sets a hook that is called after a dialog has left a page
+*/
+func (f *Menu) OnLeave(hook PageHook) *Menu {
+	f.onLeave = hook
+	return f
+}
+
+/*
+	Sets a hook that is called after every successful display of a menu
+*/
+func (f *Menu) OnNavigate(hook NavigationHook) *Menu {
+	f.onNavigate = hook
+	return f
+}
+
+/*
+	Sets a hook that is called after a menu was closed or stopped with the page it was closed at
+*/
+func (f *Menu) OnClose(hook PageHook) *Menu {
+	f.onClose = hook
+	return f
+}
+
+/*
+	Calls the hooks after a dialog was displayed at a page
+	Only internal use is intended
+*/
+func (f *Menu) navigated(from, to *Node, d *Dialog) {
+	f.reportDisplayed(to)
+	if from != to {
+		f.reportExposed(to, d)
+	}
+	f.logger.Debug("page displayed", logFields(d.owner, to, nil))
+	if from != to {
+		if from != nil && f.onLeave != nil {
+			f.onLeave(from, d)
+		}
+		if f.onEnter != nil {
+			f.onEnter(to, d)
+		}
+	}
+	if f.onNavigate != nil {
+		f.onNavigate(from, to, d)
+	}
+	to.autoRefresh(d)
+}
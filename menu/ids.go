@@ -0,0 +1,56 @@
+package menu
+
+import (
+	"strconv"
+)
+
+const (
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
+/*
+	Gets an id for a node created at a path (e.g. "order/pizza")
+	The first node at a path gets a hash of the path and the following ones get hashes of the path
+	with the number of the node, so that siblings with the same text do not collide
+	Only internal use is intended
+*/
+func (f *Menu) nodeId(path string) string {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.paths == nil {
+		f.paths = make(map[string]int)
+	}
+	n := f.paths[path]
+	f.paths[path] = n + 1
+	return hashId(path, n)
+}
+
+/*
+	Gets a short hash of a path and a number of a node at the path
+*/
+func hashId(path string, n int) string {
+	return sumId(fnvAdd(fnvOffset, path), n)
+}
+
+/*
+	Gets a short hash from a hash of a path and a number of a node at the path
+*/
+func sumId(h uint64, n int) string {
+	if n > 0 {
+		h = fnvAdd(fnvAdd(h, "#"), strconv.Itoa(n))
+	}
+	return strconv.FormatUint(h, 36)
+}
+
+/*
+	Adds a string to a 64-bit FNV-1a hash, the same hash as of hash/fnv but without allocations,
+	so paths can be hashed in parts
+*/
+func fnvAdd(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return h
+}
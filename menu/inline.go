@@ -0,0 +1,135 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"strings"
+)
+
+const inlineResults = 50
+
+var ErrInlineUnsupported = errors.New("the bot is not able to answer inline queries")
+
+/*
+	Bot methods that are required to browse menus in inline mode
+	*tb.Bot implements them
+*/
+type InlineBot interface {
+	Answer(query *tb.Query, resp *tb.QueryResponse) error
+}
+
+/*
+	Takes over inline queries of the bot, so users can browse the tree with "@<bot> <query>"
+	(see AnswerQuery and ChooseResult)
+	Inline feedback has to be enabled with @BotFather for the chosen results to be reported
+*/
+func (f *Menu) HandleInline() *Menu {
+	f.bot.Handle(tb.OnQuery, func(q *tb.Query) {
+		if err := f.AnswerQuery(q); err != nil {
+			f.fail(err, nil, nil)
+		}
+	})
+	f.bot.Handle(tb.OnChosenInlineResult, func(r *tb.ChosenInlineResult) {
+		if err := f.ChooseResult(r); err != nil {
+			f.fail(err, nil, nil)
+		}
+	})
+	return f
+}
+
+/*
+	Answers an inline query with the nodes whose labels contain the query text
+	Labels are localized in the language of the user, descriptions show the way to the node from the root
+	Provided nodes, links, nodes that can not be reached by taps and nodes the user is not authorized to use are left out
+*/
+func (f *Menu) AnswerQuery(q *tb.Query) error {
+	bot, ok := f.bot.(InlineBot)
+	if !ok {
+		return ErrInlineUnsupported
+	}
+	lang := f.resolve(q.From.LanguageCode)
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+	offset, _ := strconv.Atoi(q.Offset)
+	results := make(tb.Results, 0, inlineResults)
+	matched := 0
+	d := f.dialogOfUser(&q.From, lang)
+	f.tree.RLock()
+	f.root.walk(func(e *Node) {
+		if e.prev == nil || e.url != "" || e.query != "" || len(results) > inlineResults {
+			return
+		}
+		if !f.mayChoose(q.From.ID, e, d) {
+			return
+		}
+		label := e.label(lang)
+		if !strings.Contains(strings.ToLower(label), text) {
+			return
+		}
+		matched++
+		if matched <= offset {
+			return
+		}
+		result := &tb.ArticleResult{Title: label, Text: label, Description: e.trail(lang)}
+		result.SetResultID(e.id)
+		results = append(results, result)
+	})
+	f.tree.RUnlock()
+	response := &tb.QueryResponse{QueryID: q.ID, IsPersonal: f.authorize != nil}
+	if len(results) > inlineResults {
+		results = results[:inlineResults]
+		response.NextOffset = strconv.Itoa(offset + inlineResults)
+	}
+	response.Results = results
+	return bot.Answer(q, response)
+}
+
+/*
+	Opens the menu at the node that a user has chosen from inline results in the private chat with the user
+	The menu is displayed the same way as for a deep link (see OpenDeepLink),
+	results of nodes that have become hidden or the user is not authorized to use are ignored
+*/
+func (f *Menu) ChooseResult(r *tb.ChosenInlineResult) error {
+	var chosen *Node
+	f.tree.RLock()
+	f.root.walk(func(e *Node) {
+		if e.id == r.ResultID && e.prev != nil {
+			chosen = e
+		}
+	})
+	f.tree.RUnlock()
+	user := r.From
+	if chosen == nil || !f.mayChoose(user.ID, chosen, f.dialogOfUser(&user, f.languageOf(&user))) {
+		return nil
+	}
+	return f.open(&user, chosen)
+}
+
+/*
+	Checks if a user may open the node from inline results
+*/
+func (f *Menu) mayChoose(userID int, e *Node, d *Dialog) bool {
+	return (f.authorize == nil || f.authorize(userID, e)) && e.isReachable(d)
+}
+
+/*
+	Gets the dialog of a user, or an empty one in a specified language if the user has none,
+	so the visibility of nodes is checked the same way as in the menu
+*/
+func (f *Menu) dialogOfUser(user *tb.User, lang string) *Dialog {
+	if d, ok := f.GetDialog(user.Recipient()); ok {
+		return d
+	}
+	return &Dialog{Language: lang, owner: user.Recipient()}
+}
+
+/*
+	Gets localized labels of the node's ancestors below the root
+*/
+func (e *Node) trail(lang string) string {
+	labels := make([]string, 0)
+	for node := e.prev; node != nil && node.prev != nil; node = node.prev {
+		labels = append([]string{node.label(lang)}, labels...)
+	}
+	return strings.Join(labels, " › ")
+}
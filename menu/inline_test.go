@@ -0,0 +1,41 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestInlineLeavesOutUnreachableNodes(t *testing.T) {
+	flow, bot := newFlow(t, "inline")
+	root := flow.GetRoot()
+	shown := root.AddSub("shop", nil)
+	hidden := root.AddSub("secret", nil).SetHidden(true).AddSub("vault", nil)
+	invisible := root.AddSub("beta", nil).SetVisible(func(e *menu.Node, d *menu.Dialog) bool { return false })
+	denied := root.AddSub("admin", nil)
+	flow.WithAuthorizer(func(userID int, node *menu.Node) bool { return node != denied })
+	flow.Build("en")
+	user := tb.User{ID: 1}
+	if err := flow.AnswerQuery(&tb.Query{ID: "1", From: user}); err != nil {
+		t.Fatal(err)
+	}
+	results := bot.Answers[0].Results
+	if len(results) != 1 || results[0].ResultID() != shown.GetId() {
+		t.Fatalf("answered %d results instead of the shop", len(results))
+	}
+	for _, node := range []*menu.Node{hidden, invisible, denied} {
+		if err := flow.ChooseResult(&tb.ChosenInlineResult{From: user, ResultID: node.GetId()}); err != nil {
+			t.Fatal(err)
+		}
+		if bot.Last(&user) != nil {
+			t.Fatalf("%s is opened from an inline result", node.GetId())
+		}
+	}
+	if err := flow.ChooseResult(&tb.ChosenInlineResult{From: user, ResultID: shown.GetId()}); err != nil {
+		t.Fatal(err)
+	}
+	if bot.Last(&user) == nil {
+		t.Fatal("the shop is not opened from an inline result")
+	}
+}
@@ -0,0 +1,186 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Handler of a text message sent by a user while the dialog awaits an input
+	The result is treated the same way as a result of a node's callback
+	Stay keeps the dialog awaiting the input (e.g. when the text is invalid)
+*/
+type TextHandler func(e *Node, m *tb.Message) int
+
+/*
+	Settings of a node that asks a user for a free text or a file
+*/
+type textInput struct {
+	prompt  string
+	handler TextHandler
+	kinds   []FileKind
+	maxSize int
+	types   []string
+	request string
+	button  string
+}
+
+/*
+	Checks if a message is the kind of input the node awaits
+*/
+func (in *textInput) accepts(m *tb.Message) bool {
+	switch {
+	case in.request == locationRequest:
+		return m.Location != nil
+	case in.request == contactRequest:
+		return m.Contact != nil
+	case in.kinds != nil:
+		return len(m.Text) < 1
+	}
+	return len(m.Text) > 0
+}
+
+/*
+	Makes the node ask a user for a free text when pressed
+	The caption of the menu is replaced by the prompt until the text is received
+	Text messages have to be passed to Menu.Process for the input to be handled
+	Returns the current node
+*/
+func (e *Node) AwaitText(prompt string, handler TextHandler) *Node {
+	e.input = &textInput{prompt: prompt, handler: handler}
+	return e
+}
+
+/*
+	Process a text message, a file, a location or a contact from a user that the menu awaits
+	Typed numbers of buttons are handled as taps while the buttons are numbered (see WithNumbering)
+	The message is handled in turn with taps of the dialog, so it must not be processed from an endpoint
+	Returns true only if the message was consumed by the menu
+*/
+func (f *Menu) Process(m *tb.Message) bool {
+	if m == nil || m.Sender == nil {
+		return false
+	}
+	d, ok := f.DialogOfMessage(m)
+	if !ok {
+		return false
+	}
+	consumed := false
+	d.exclusive(func() {
+		consumed = f.process(m, d)
+	})
+	return consumed
+}
+
+/*
+	Process a message in the turn of the dialog
+*/
+func (f *Menu) process(m *tb.Message, d *Dialog) bool {
+	if d.input == nil {
+		return m.Text != "" && f.shortcut(m, d)
+	}
+	if !d.input.input.accepts(m) || !f.gate.enter() {
+		return false
+	}
+	defer f.gate.leave()
+	d.touch()
+	e := d.input
+	if key, fallback := e.input.reject(m); key != "" {
+		f.pace(m.Sender)
+		if _, err := f.bot.Send(m.Sender, f.translate(d.Language, key, fallback)); err != nil {
+			f.fail(err, nil, e)
+		}
+		return true
+	}
+	e.input.capture(e, d, m)
+	shown := d.Message
+	result := e.input.handler(e, m)
+	if result == Stay {
+		return true
+	}
+	d.input = nil
+	if e.input.request != "" {
+		page := e
+		if !e.HasPage() || result == Back && e.prev != nil {
+			page = e.prev
+		}
+		if err := f.resendMenu(m.Sender, d, page); err != nil {
+			f.fail(err, nil, e)
+		}
+		return true
+	}
+	if d.Message == shown {
+		d.setText(d.saved)
+	}
+	if result == Back {
+		d.markPending(e)
+		if err := e.back(m.Sender, d); err != nil {
+			f.fail(err, nil, e)
+		}
+		return true
+	}
+	page := e
+	if !e.HasPage() {
+		page = e.prev
+	}
+	if d.Message != shown && d.Position == page {
+		// the handler has already updated the menu
+		return true
+	}
+	if err := e.update(m.Sender, d, page); err != nil {
+		f.fail(err, nil, e)
+	}
+	return true
+}
+
+/*
+	Stops awaiting an input from the user and restores the caption
+	Only internal use is intended
+*/
+func (d *Dialog) cancelInput() {
+	if d.prompt != nil {
+		d.input.flow.bot.Delete(d.prompt)
+		d.prompt = nil
+	}
+	if d.input != nil && d.input.input.request != "" {
+		d.input = nil
+		return
+	}
+	if d.input != nil {
+		d.input = nil
+		d.setText(d.saved)
+	}
+}
+
+/*
+	Handler for menu buttons that ask for a text input
+*/
+func (e *Node) handleInput(c *tb.Callback) {
+	d, ok := e.accept(c)
+	if !ok {
+		return
+	}
+	audited := e.flow.auditTap(e, c, d)
+	err := e.await(c.Sender, d)
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	audited(d, AuditInput, err)
+}
+
+/*
+	Makes the dialog await a text for the node and displays the prompt
+*/
+func (e *Node) await(to tb.Recipient, d *Dialog) error {
+	if e.input.request != "" {
+		d.cancelInput()
+		return e.request(to, d)
+	}
+	if d.input == nil {
+		d.saved = d.GetCaption()
+	}
+	d.input = e
+	if e.input.prompt != "" || e.input.kinds == nil {
+		d.setText(e.input.prompt)
+	}
+	return e.update(to, d, d.Position)
+}
@@ -0,0 +1,237 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+	"sync"
+)
+
+const payloadSeparator = "|"
+
+var ErrPaymentsUnsupported = errors.New("the bot is not able to accept payments")
+
+/*
+	Bot methods that are required to accept payments for invoices of menus
+	*tb.Bot implements them
+*/
+type PaymentBot interface {
+	Accept(query *tb.PreCheckoutQuery, errorMessage ...string) error
+}
+
+/*
+	Routers of payments by bots, so flows that share a bot get payments of their own invoices
+*/
+var (
+	paymentRouters   = make(map[Bot]*paymentRouter)
+	paymentRoutersMx sync.Mutex
+)
+
+/*
+	A router that handles OnCheckout and OnPayment events of a bot once for all of its flows
+	and passes them to the flow that has sent the invoice
+*/
+type paymentRouter struct {
+	flows []*Menu
+	mx    sync.RWMutex
+}
+
+/*
+	An invoice of a node along with the callbacks of its payment
+*/
+type payment struct {
+	invoice  tb.Invoice
+	checkout func(q *tb.PreCheckoutQuery) error
+	onPaid   func(e *Node, m *tb.Message) int
+}
+
+/*
+	Adds a new node that sends an invoice to the user when pressed
+	OnCheckout and OnPayment events of the bot are handled by the menu to route payments back to the node,
+	so the payload of the invoice is prefixed with the node's unique
+	The bot has to implement PaymentBot, flows that share a bot get payments of their own invoices
+	When the payment succeeds the callback is called with the payment message,
+	its result is treated as a result of a node's callback, so Forward opens the node's page (e.g. a "thank you" page)
+	Returns the new node
+*/
+func (e *Node) AddInvoice(text string, invoice tb.Invoice, onPaid func(e *Node, m *tb.Message) int) *Node {
+	node := e.AddSub(text, sendInvoice)
+	node.payment = &payment{invoice: invoice, onPaid: onPaid}
+	e.flow.addInvoice(node)
+	return node
+}
+
+/*
+	Sets a function that checks an order before the payment for the node's invoice is made
+	(e.g. if the goods are still in stock), the error is displayed to the user when the order is rejected
+	Returns the current node
+*/
+func (e *Node) SetCheckout(checkout func(q *tb.PreCheckoutQuery) error) *Node {
+	if e.payment != nil {
+		e.payment.checkout = checkout
+	}
+	return e
+}
+
+/*
+	Gets the payload of an invoice without the prefix added by the menu
+*/
+func Payload(payload string) string {
+	if i := strings.Index(payload, payloadSeparator); i >= 0 {
+		return payload[i+len(payloadSeparator):]
+	}
+	return payload
+}
+
+/*
+	Endpoint for invoice nodes that sends the invoice of the node
+	The payload of the invoice is prefixed with the node's unique
+*/
+func sendInvoice(e *Node, c *tb.Callback) int {
+	invoice := e.payment.invoice
+	invoice.Payload = e.GetUnique() + payloadSeparator + invoice.Payload
+	e.flow.pace(c.Sender)
+	if _, err := e.flow.bot.Send(c.Sender, &invoice); err != nil {
+		e.flow.fail(err, c, e)
+	}
+	return Stay
+}
+
+/*
+	Registers an invoice node, so payments are routed to it
+*/
+func (f *Menu) addInvoice(node *Node) {
+	if _, ok := f.bot.(PaymentBot); !ok {
+		f.fail(ErrPaymentsUnsupported, nil, node)
+		return
+	}
+	f.mx.Lock()
+	first := f.invoices == nil
+	if first {
+		f.invoices = make(map[string]*Node)
+	}
+	f.invoices[node.GetUnique()] = node
+	f.mx.Unlock()
+	if first {
+		routePayments(f)
+	}
+}
+
+/*
+	Adds a flow to the payment router of its bot, the router is registered with the bot once
+*/
+func routePayments(f *Menu) {
+	paymentRoutersMx.Lock()
+	r, ok := paymentRouters[f.bot]
+	if !ok {
+		r = &paymentRouter{}
+		paymentRouters[f.bot] = r
+		f.bot.Handle(tb.OnCheckout, r.handleCheckout)
+		f.bot.Handle(tb.OnPayment, r.handlePayment)
+	}
+	paymentRoutersMx.Unlock()
+	r.mx.Lock()
+	r.flows = append(r.flows, f)
+	r.mx.Unlock()
+}
+
+/*
+	Finds the flow that has sent an invoice by the payload of a payment
+*/
+func (r *paymentRouter) flowOf(payload string) (*Menu, bool) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	for _, f := range r.flows {
+		if _, ok := f.invoiceOf(payload); ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+/*
+	Passes a pre-checkout query to the flow of the invoice
+*/
+func (r *paymentRouter) handleCheckout(q *tb.PreCheckoutQuery) {
+	if f, ok := r.flowOf(q.Payload); ok {
+		f.handleCheckout(q)
+	}
+}
+
+/*
+	Passes a successful payment to the flow of the invoice
+*/
+func (r *paymentRouter) handlePayment(m *tb.Message) {
+	if m.Payment == nil {
+		return
+	}
+	if f, ok := r.flowOf(m.Payment.Payload); ok {
+		f.handlePayment(m)
+	}
+}
+
+/*
+	Finds an invoice node of the flow by the payload of a payment
+*/
+func (f *Menu) invoiceOf(payload string) (*Node, bool) {
+	i := strings.Index(payload, payloadSeparator)
+	if i < 0 {
+		return nil, false
+	}
+	f.mx.RLock()
+	node, ok := f.invoices[payload[:i]]
+	f.mx.RUnlock()
+	return node, ok
+}
+
+/*
+	Handler for pre-checkout queries that accepts orders of invoice nodes
+*/
+func (f *Menu) handleCheckout(q *tb.PreCheckoutQuery) {
+	node, ok := f.invoiceOf(q.Payload)
+	if !ok {
+		return
+	}
+	bot, ok := f.bot.(PaymentBot)
+	if !ok {
+		f.fail(ErrPaymentsUnsupported, nil, node)
+		return
+	}
+	var reasons []string
+	if check := node.payment.checkout; check != nil {
+		if reason := check(q); reason != nil {
+			reasons = append(reasons, reason.Error())
+		}
+	}
+	if err := bot.Accept(q, reasons...); err != nil {
+		f.fail(err, nil, node)
+	}
+}
+
+/*
+	Handler for successful payments that continues the dialog of the payer
+*/
+func (f *Menu) handlePayment(m *tb.Message) {
+	if m.Payment == nil || m.Sender == nil {
+		return
+	}
+	node, ok := f.invoiceOf(m.Payment.Payload)
+	if !ok || node.payment.onPaid == nil {
+		return
+	}
+	result := node.payment.onPaid(node, m)
+	d, ok := f.DialogOfMessage(m)
+	if !ok {
+		return
+	}
+	var err error
+	if result == Forward {
+		d.markPending(node)
+		err = node.next(m.Sender, d)
+	} else if result == Back {
+		err = node.back(m.Sender, d)
+	}
+	if err != nil {
+		f.fail(err, nil, node)
+	}
+}
@@ -0,0 +1,40 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Sets a function that picks a language for a user that has no dialog yet (e.g. from a database of settings)
+	By default the language of the user's Telegram profile is mapped to a built locale
+	the same way as for SetLanguage, so unknown languages fall back to the default locale
+*/
+func (f *Menu) WithLanguageDetector(detect func(user *tb.User) string) *Menu {
+	f.detectLanguage = detect
+	return f
+}
+
+/*
+	Gets a language for a user at the first interaction with the menu
+	Only internal use is intended
+*/
+func (f *Menu) languageOf(user *tb.User) string {
+	if user == nil {
+		return f.GetDefaultLocale()
+	}
+	if f.detectLanguage != nil {
+		return f.resolve(f.detectLanguage(user))
+	}
+	return f.resolve(user.LanguageCode)
+}
+
+/*
+	Gets a language for a recipient, users get the language detected from their profile
+	and other recipients (e.g. group chats) get the default locale
+*/
+func (f *Menu) languageFor(to tb.Recipient) string {
+	if user, ok := to.(*tb.User); ok {
+		return f.languageOf(user)
+	}
+	return f.GetDefaultLocale()
+}
@@ -0,0 +1,37 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A layout arranges buttons of a page in rows
+*/
+type LayoutFunc func(buttons []tb.InlineButton) [][]tb.InlineButton
+
+/*
+	A default layout that puts every button in a row of its own
+*/
+func SingleColumn(buttons []tb.InlineButton) [][]tb.InlineButton {
+	return Columns(1)(buttons)
+}
+
+/*
+	Creates a layout that puts up to n buttons in a row
+*/
+func Columns(n int) LayoutFunc {
+	if n < 1 {
+		n = 1
+	}
+	return func(buttons []tb.InlineButton) [][]tb.InlineButton {
+		rows := make([][]tb.InlineButton, 0, (len(buttons)+n-1)/n)
+		for i := 0; i < len(buttons); i += n {
+			end := i + n
+			if end > len(buttons) {
+				end = len(buttons)
+			}
+			rows = append(rows, buttons[i:end:end])
+		}
+		return rows
+	}
+}
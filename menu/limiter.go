@@ -0,0 +1,194 @@
+package menu
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const idleBuckets = 1024
+
+/*
+	Limits edits of menu messages per chat with a token bucket
+	A chat gets a token every interval and may spend up to burst tokens at once,
+	an edit that has to wait for a token is deferred and displays the latest state of the dialog when it is made,
+	so rapid taps are coalesced into a single edit
+	Zero interval disables the limit, though edits are still delayed after Telegram asks to retry later
+*/
+func (f *Menu) WithEditLimit(interval time.Duration, burst int) *Menu {
+	if burst < 1 {
+		burst = 1
+	}
+	f.limiter.mx.Lock()
+	f.limiter.interval = interval
+	f.limiter.burst = burst
+	f.limiter.mx.Unlock()
+	return f
+}
+
+/*
+	Checks if the menu message of a dialog may be edited right away
+	Otherwise the edit is deferred until a token comes, so the turn of the dialog is not held while waiting,
+	and the deferred edit displays the state of the dialog at that time
+	Returns false if the edit is left to the deferred one
+*/
+func (f *Menu) throttle(d *Dialog) bool {
+	if d.Message == nil || d.Message.Chat == nil {
+		return true
+	}
+	chat := d.Message.Chat
+	allowed, delay := f.limiter.take(chat.ID, d)
+	if delay > 0 {
+		d.after(delay, func() {
+			f.limiter.release(chat.ID, d)
+			if d.isClosed() || d.Message == nil {
+				return
+			}
+			if err := f.root.redraw(chat, d); err != nil {
+				f.fail(err, nil, d.Position)
+			}
+		})
+	}
+	return allowed
+}
+
+/*
+	A set of token buckets of chats
+*/
+type limiter struct {
+	interval time.Duration
+	burst    int
+	buckets  map[int64]*bucket
+	mx       sync.Mutex
+}
+
+/*
+	A token bucket of a chat with the dialogs whose edits are deferred until a token comes
+*/
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	until   time.Time
+	queued  map[*Dialog]bool
+}
+
+/*
+	Creates a limiter with no limit
+*/
+func newLimiter() *limiter {
+	return &limiter{burst: 1, buckets: make(map[int64]*bucket)}
+}
+
+/*
+	Takes a token for an edit of a dialog in a chat
+	Returns false along with the time to wait if there is no token, the edit has to be deferred for that time then,
+	or false and zero if a deferred edit of the dialog is already waiting
+*/
+func (l *limiter) take(chat int64, d *Dialog) (bool, time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	b := l.bucket(chat)
+	if b.queued[d] {
+		return false, 0
+	}
+	if delay := l.delay(b, time.Now()); delay > 0 {
+		b.queued[d] = true
+		return false, delay
+	}
+	if l.interval > 0 {
+		b.tokens--
+	}
+	return true, 0
+}
+
+/*
+	Lets a dialog whose deferred edit has come take tokens again
+*/
+func (l *limiter) release(chat int64, d *Dialog) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	delete(l.bucket(chat).queued, d)
+}
+
+/*
+	Blocks edits in a chat for a specified time after Telegram asked to retry later
+*/
+func (l *limiter) block(chat int64, delay time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	b := l.bucket(chat)
+	b.until = time.Now().Add(delay)
+	b.tokens = 0
+}
+
+/*
+	Gets a bucket of a chat, creating a full one if there is none
+	Full buckets with no queued edits are dropped when there are too many of them
+*/
+func (l *limiter) bucket(chat int64) *bucket {
+	if b, ok := l.buckets[chat]; ok {
+		return b
+	}
+	if len(l.buckets) >= idleBuckets {
+		now := time.Now()
+		for id, b := range l.buckets {
+			if len(b.queued) == 0 && l.delay(b, now) <= 0 && b.tokens >= float64(l.burst) {
+				delete(l.buckets, id)
+			}
+		}
+	}
+	b := &bucket{
+		tokens:  float64(l.burst),
+		updated: time.Now(),
+		queued:  make(map[*Dialog]bool),
+	}
+	l.buckets[chat] = b
+	return b
+}
+
+/*
+	Refills a bucket and calculates how long an edit has to wait for a token
+*/
+func (l *limiter) delay(b *bucket, now time.Time) time.Duration {
+	if now.Before(b.until) {
+		return b.until.Sub(now)
+	}
+	if l.interval <= 0 {
+		return 0
+	}
+	b.tokens += float64(now.Sub(b.updated)) / float64(l.interval)
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.updated = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(l.interval))
+}
+
+/*
+	Gets the time Telegram asked to wait before the next request
+	Returns false if there is no error or it is not caused by flood control
+*/
+func retry(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	text := err.Error()
+	i := strings.Index(text, "retry after ")
+	if i < 0 {
+		return 0, false
+	}
+	text = text[i+len("retry after "):]
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	seconds, err := strconv.Atoi(text[:end])
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
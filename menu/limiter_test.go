@@ -0,0 +1,29 @@
+package menu_test
+
+import (
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestEditLimitDefersEdit(t *testing.T) {
+	flow, bot := newFlow(t, "limit")
+	flow.GetRoot().AddSub("settings", nil).AddSub("advanced", nil).Add("reset", stay)
+	flow.WithEditLimit(300*time.Millisecond, 1).Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.Start(user, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	if err := bot.Tap(flow, user, "settings/advanced"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed >= 150*time.Millisecond {
+		t.Fatalf("the tap waits %v for a token of its chat", elapsed)
+	}
+	waitButton(t, bot, user, flow.Find("settings/advanced/reset"))
+}
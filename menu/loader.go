@@ -0,0 +1,150 @@
+package menu
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnknownEndpoint = errors.New("endpoint is not provided")
+
+/*
+	A declarative definition of a node and its children
+	The text is a locale key of the node the same way as for NewNode
+	An endpoint is referred by its name, "back" and "forward" are available by default
+	A label is displayed as is in every locale instead of the localized text
+*/
+type Definition struct {
+	Text     string        `json:"text" yaml:"text"`
+	Endpoint string        `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	URL      string        `json:"url,omitempty" yaml:"url,omitempty"`
+	Query    string        `json:"query,omitempty" yaml:"query,omitempty"`
+	Caption  string        `json:"caption,omitempty" yaml:"caption,omitempty"`
+	Label    string        `json:"label,omitempty" yaml:"label,omitempty"`
+	Hidden   bool          `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Columns  int           `json:"columns,omitempty" yaml:"columns,omitempty"`
+	PageSize int           `json:"page_size,omitempty" yaml:"page_size,omitempty"`
+	Nodes    []*Definition `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+}
+
+/*
+	Builds the tree of the flow from a YAML or JSON document that defines the root node
+	Files with the .json extension are parsed as JSON, all others as YAML
+	The flow still has to be built for every locale afterwards
+*/
+func (f *Menu) LoadFromFile(path string, endpoints map[string]Callback) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	def := &Definition{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, def)
+	} else {
+		err = yaml.Unmarshal(data, def)
+	}
+	if err != nil {
+		return errors.Wrap(err, path)
+	}
+	return f.Load(def, endpoints)
+}
+
+/*
+	Builds the tree of the flow from a definition of the root node
+	The text and the endpoint of the root definition are ignored
+*/
+func (f *Menu) Load(root *Definition, endpoints map[string]Callback) error {
+	return f.root.load(root, endpoints)
+}
+
+/*
+	Applies a definition to the node and adds its children
+*/
+func (e *Node) load(def *Definition, endpoints map[string]Callback) error {
+	e.endpointName = def.Endpoint
+	e.title, e.hidden = def.Label, def.Hidden
+	if def.Caption != "" {
+		e.SetCaptionKey(def.Caption)
+	}
+	if def.Columns > 0 {
+		e.SetColumns(def.Columns)
+	}
+	if def.PageSize > 0 {
+		e.SetPageSize(def.PageSize)
+	}
+	for _, child := range def.Nodes {
+		if child.URL != "" {
+			e.AddURL(child.Text, child.URL)
+			e.nodes[len(e.nodes)-1].SetLabel(child.Label).SetHidden(child.Hidden)
+			continue
+		}
+		if child.Query != "" {
+			e.AddSwitchInline(child.Text, child.Query)
+			e.nodes[len(e.nodes)-1].SetLabel(child.Label).SetHidden(child.Hidden)
+			continue
+		}
+		endpoint, err := e.flow.endpoint(child.Endpoint, endpoints)
+		if err != nil {
+			return errors.Wrap(err, e.path+"/"+child.Text)
+		}
+		if err := e.AddSub(child.Text, endpoint).load(child, endpoints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	Looks up an endpoint by its name
+	An empty name means the node has no endpoint
+*/
+func (f *Menu) endpoint(name string, endpoints map[string]Callback) (Callback, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if endpoint, ok := endpoints[name]; ok {
+		return endpoint, nil
+	}
+	switch name {
+	case "back":
+		return f.HandleBack, nil
+	case "forward":
+		return f.HandleForward, nil
+	}
+	return nil, errors.Wrap(ErrUnknownEndpoint, name)
+}
+
+/*
+	Gets a definition of the flow's tree that Load builds the same tree from (e.g. to save a tree edited at runtime)
+	Endpoints are named the way they were loaded, so nodes added in code have no endpoint names,
+	provided nodes and custom layouts are not a part of the definition
+*/
+func (f *Menu) Definition() *Definition {
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	return f.root.definition()
+}
+
+/*
+	Gets a definition of the node and its children
+*/
+func (e *Node) definition() *Definition {
+	def := &Definition{
+		Text:     e.text,
+		Endpoint: e.endpointName,
+		URL:      e.url,
+		Query:    e.query,
+		Caption:  e.captionKey,
+		Label:    e.title,
+		Hidden:   e.hidden,
+		Columns:  e.columns,
+		PageSize: e.pageSize,
+	}
+	for _, child := range e.nodes {
+		def.Nodes = append(def.Nodes, child.definition())
+	}
+	return def
+}
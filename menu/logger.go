@@ -0,0 +1,86 @@
+package menu
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+/*
+	Structured fields of a log entry (e.g. "recipient", "node", "error")
+*/
+type Fields map[string]interface{}
+
+/*
+	A structured logger that the menu writes to
+	Adapters for zap, zerolog and other loggers only have to pass the fields through
+*/
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+/*
+	Sets a logger for the menu
+	By default info and error entries are written with the standard log package and debug entries are dropped
+*/
+func (f *Menu) SetLogger(logger Logger) *Menu {
+	if logger == nil {
+		logger = stdLogger{}
+	}
+	f.logger = logger
+	return f
+}
+
+/*
+	Gets the logger of the menu
+*/
+func (f *Menu) GetLogger() Logger {
+	return f.logger
+}
+
+/*
+	Collects fields of a log entry about a user and a node
+*/
+func logFields(recipient string, node *Node, err error) Fields {
+	fields := Fields{}
+	if recipient != "" {
+		fields["recipient"] = recipient
+	}
+	if node != nil {
+		fields["node"] = node.path
+	}
+	if err != nil {
+		fields["error"] = err
+	}
+	return fields
+}
+
+/*
+	A logger that writes to the standard log package
+*/
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, fields Fields) {}
+
+func (stdLogger) Info(msg string, fields Fields) {
+	log.Println(msg, formatFields(fields))
+}
+
+func (stdLogger) Error(msg string, fields Fields) {
+	log.Println(msg, formatFields(fields))
+}
+
+/*
+	Formats fields as sorted key=value pairs
+*/
+func formatFields(fields Fields) string {
+	pairs := make([]string, 0, len(fields))
+	for key, value := range fields {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
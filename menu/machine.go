@@ -0,0 +1,569 @@
+package menu
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMachine = errors.New("invalid state machine")
+
+/*
+	Results of a tap that transitions of a state machine are labeled with
+*/
+const (
+	ResultStay    = "stay"
+	ResultForward = "forward"
+	ResultBack    = "back"
+)
+
+const scxmlNamespace = "http://www.w3.org/2005/07/scxml"
+
+/*
+	A format of an exported state machine
+*/
+type MachineFormat int
+
+const (
+	MachineJSON MachineFormat = iota
+	MachineSCXML
+)
+
+/*
+	The flow as a state machine: a state for every node and transitions by the results of taps
+	States are identified by names that are valid XML identificators (e.g. "root.order.pizza"),
+	the root is the only state that is not a child of another state
+*/
+type StateMachine struct {
+	Name    string   `json:"name,omitempty"`
+	Initial string   `json:"initial"`
+	States  []*State `json:"states"`
+}
+
+/*
+	A state of a machine that stands for a node
+	Children are listed in the order of their buttons, a mounted state is a child of every page it is mounted under
+	The settings of the node are kept the same way as in a Definition
+*/
+type State struct {
+	Id          string        `json:"id"`
+	Text        string        `json:"text,omitempty"`
+	Mounted     bool          `json:"mounted,omitempty"`
+	Children    []string      `json:"children,omitempty"`
+	Endpoint    string        `json:"endpoint,omitempty"`
+	URL         string        `json:"url,omitempty"`
+	Query       string        `json:"query,omitempty"`
+	Caption     string        `json:"caption,omitempty"`
+	Label       string        `json:"label,omitempty"`
+	Hidden      bool          `json:"hidden,omitempty"`
+	Transitions []*Transition `json:"transitions,omitempty"`
+}
+
+/*
+	A transition of a state by a tap on a button of a child (the event is the id of the child)
+	The target is the state whose page is displayed after the tap ended with the result
+*/
+type Transition struct {
+	Event  string `json:"event"`
+	Result string `json:"result"`
+	Target string `json:"target"`
+}
+
+var stateIdPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+/*
+	Gets the tree of the flow as a state machine
+	Endpoints are named the same way as in Definition, an endpoint that is not "back" or "forward"
+	may end with any result, so it has a transition for each of them
+	Provided nodes are generated at display time, so only their parents are a part of the machine
+*/
+func (f *Menu) StateMachine() *StateMachine {
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	var nodes []*Node
+	ids := make(map[*Node]string)
+	used := make(map[string]bool)
+	parents := make(map[*Node][]*Node)
+	var walk func(e *Node)
+	walk = func(e *Node) {
+		if _, ok := ids[e]; ok {
+			return
+		}
+		ids[e] = stateId(e, used)
+		nodes = append(nodes, e)
+		for _, child := range e.nodes {
+			parents[child] = append(parents[child], e)
+			walk(child)
+		}
+	}
+	walk(f.root)
+	m := &StateMachine{Name: f.id, Initial: ids[f.root]}
+	for _, e := range nodes {
+		m.States = append(m.States, e.state(ids, parents[e]))
+	}
+	return m
+}
+
+/*
+	Gets a state of the node with transitions by taps on its children
+	Back buttons of a mounted page lead to every page it is mounted under
+*/
+func (e *Node) state(ids map[*Node]string, parents []*Node) *State {
+	s := &State{
+		Id:       ids[e],
+		Mounted:  e.mounted,
+		Endpoint: e.endpointName,
+		URL:      e.url,
+		Query:    e.query,
+		Caption:  e.captionKey,
+		Label:    e.title,
+		Hidden:   e.hidden,
+	}
+	if e.prev != nil {
+		s.Text = e.text
+	}
+	backs := parents
+	if len(backs) == 0 {
+		backs = []*Node{e}
+	}
+	for _, child := range e.nodes {
+		s.Children = append(s.Children, ids[child])
+		forward := e
+		if child.HasPage() {
+			forward = child
+		}
+		var results []string
+		switch {
+		case child.url != "" || child.query != "" || child.input != nil:
+			// the dialog stays on the page while the button is handled by Telegram or awaits a text
+			results = []string{ResultStay}
+		case child.endpoint == nil || child.endpointName == "forward":
+			results = []string{ResultForward}
+		case child.endpointName == "back":
+			results = []string{ResultBack}
+		default:
+			results = []string{ResultStay, ResultForward, ResultBack}
+		}
+		for _, result := range results {
+			targets := []*Node{e}
+			if result == ResultForward {
+				targets = []*Node{forward}
+			} else if result == ResultBack {
+				targets = backs
+			}
+			for _, target := range targets {
+				s.Transitions = append(s.Transitions, &Transition{Event: ids[child], Result: result, Target: ids[target]})
+			}
+		}
+	}
+	return s
+}
+
+/*
+	Gets an identificator of the node's state that is unique within the machine
+	It follows the path of the node, characters that are not allowed in XML identificators are replaced
+*/
+func stateId(e *Node, used map[string]bool) string {
+	id := "root"
+	if path := e.relativePath(); path != "" {
+		id += "." + strings.Map(func(r rune) rune {
+			switch {
+			case r == '/':
+				return '.'
+			case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-':
+				return r
+			}
+			return '_'
+		}, path)
+	}
+	unique := id
+	for n := 2; used[unique]; n++ {
+		unique = id + "-" + strconv.Itoa(n)
+	}
+	used[unique] = true
+	return unique
+}
+
+/*
+	Checks that the states form a tree with a single root that is the initial state,
+	transitions lead to known states by known results and every state can be reached from the initial one
+	Returns an error that lists all of the problems
+*/
+func (m *StateMachine) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	states := make(map[string]*State, len(m.States))
+	for _, s := range m.States {
+		if !stateIdPattern.MatchString(s.Id) {
+			add("state %q has an invalid id", s.Id)
+			continue
+		}
+		if _, ok := states[s.Id]; ok {
+			add("state %q is repeated", s.Id)
+			continue
+		}
+		states[s.Id] = s
+	}
+	owners := make(map[string]int)
+	for _, s := range m.States {
+		if states[s.Id] != s {
+			continue
+		}
+		children := make(map[string]bool, len(s.Children))
+		for _, id := range s.Children {
+			child, ok := states[id]
+			if !ok {
+				add("state %q has unknown child %q", s.Id, id)
+				continue
+			}
+			children[id] = true
+			if !child.Mounted {
+				owners[id]++
+			}
+		}
+		for _, t := range s.Transitions {
+			if !children[t.Event] {
+				add("state %q has no child %q", s.Id, t.Event)
+			}
+			if _, ok := states[t.Target]; !ok {
+				add("state %q leads to unknown state %q", s.Id, t.Target)
+			}
+			if t.Result != ResultStay && t.Result != ResultForward && t.Result != ResultBack {
+				add("state %q has unknown result %q", s.Id, t.Result)
+			}
+		}
+	}
+	roots := m.roots()
+	if len(roots) != 1 {
+		add("the machine has %d states that are not children of other states instead of one root: %s",
+			len(roots), strings.Join(roots, ", "))
+	}
+	for _, s := range m.States {
+		if states[s.Id] != s {
+			continue
+		}
+		if owners[s.Id] > 1 {
+			add("state %q is a child of %d states, only mounted states may be", s.Id, owners[s.Id])
+		}
+		isRoot := len(roots) == 1 && roots[0] == s.Id
+		if !isRoot && (s.Text == "" || strings.Contains(s.Text, "/")) {
+			add("state %q has an invalid text %q", s.Id, s.Text)
+		}
+	}
+	if len(roots) == 1 {
+		for _, id := range m.cyclic(states, roots[0]) {
+			add("state %q is a child of itself", id)
+		}
+	}
+	if _, ok := states[m.Initial]; !ok {
+		add("initial state %q does not exist", m.Initial)
+	} else if len(roots) == 1 && roots[0] != m.Initial {
+		add("initial state %q is not the root %q", m.Initial, roots[0])
+	}
+	for _, id := range m.Unreachable() {
+		add("state %q is unreachable", id)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Wrap(ErrInvalidMachine, strings.Join(problems, "; "))
+}
+
+/*
+	Gets the states that are not children of any state, a valid machine has only the root
+*/
+func (m *StateMachine) roots() []string {
+	children := make(map[string]bool)
+	for _, s := range m.States {
+		for _, id := range s.Children {
+			children[id] = true
+		}
+	}
+	var roots []string
+	for _, s := range m.States {
+		if !children[s.Id] {
+			roots = append(roots, s.Id)
+		}
+	}
+	return roots
+}
+
+/*
+	Gets the states that are their own descendants, so the tree can not be built
+*/
+func (m *StateMachine) cyclic(states map[string]*State, root string) []string {
+	var cycles []string
+	done := make(map[string]bool)
+	parents := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if parents[id] {
+			cycles = append(cycles, id)
+			return
+		}
+		s, ok := states[id]
+		if !ok || done[id] {
+			return
+		}
+		parents[id] = true
+		for _, child := range s.Children {
+			visit(child)
+		}
+		delete(parents, id)
+		done[id] = true
+	}
+	visit(root)
+	return cycles
+}
+
+/*
+	Gets the states that can not be reached from the initial one in order of the machine
+	A state is reached when its page is displayed or its button is tapped on a page that is reached
+*/
+func (m *StateMachine) Unreachable() []string {
+	states := make(map[string]*State, len(m.States))
+	for _, s := range m.States {
+		if _, ok := states[s.Id]; !ok {
+			states[s.Id] = s
+		}
+	}
+	reached := map[string]bool{m.Initial: true}
+	queue := []string{m.Initial}
+	for len(queue) > 0 {
+		s, ok := states[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+		for _, t := range s.Transitions {
+			for _, id := range []string{t.Event, t.Target} {
+				if !reached[id] {
+					reached[id] = true
+					queue = append(queue, id)
+				}
+			}
+		}
+	}
+	var unreachable []string
+	for _, s := range m.States {
+		if !reached[s.Id] {
+			unreachable = append(unreachable, s.Id)
+		}
+	}
+	return unreachable
+}
+
+/*
+	Writes the flow as a state machine in JSON or SCXML
+	SCXML states keep the texts, children and settings of nodes in their data models,
+	events are named "<result>.<child id>"
+*/
+func (f *Menu) ExportStateMachine(w io.Writer, format MachineFormat) error {
+	m := f.StateMachine()
+	switch format {
+	case MachineJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	case MachineSCXML:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(m.scxml()); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return ErrUnknownFormat
+}
+
+/*
+	Builds the tree of the flow from a state machine in JSON or SCXML (e.g. designed in an external tool)
+	The machine is validated first, endpoints are looked up the same way as for Load
+	The flow still has to be built for every locale afterwards
+*/
+func (f *Menu) ImportStateMachine(r io.Reader, format MachineFormat, endpoints map[string]Callback) error {
+	m := &StateMachine{}
+	switch format {
+	case MachineJSON:
+		if err := json.NewDecoder(r).Decode(m); err != nil {
+			return err
+		}
+	case MachineSCXML:
+		doc := &scxml{}
+		if err := xml.NewDecoder(r).Decode(doc); err != nil {
+			return err
+		}
+		m = doc.machine()
+	default:
+		return ErrUnknownFormat
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	states := make(map[string]*State, len(m.States))
+	for _, s := range m.States {
+		states[s.Id] = s
+	}
+	return f.root.loadState(states[m.Initial], states, endpoints, make(map[string]*Node))
+}
+
+/*
+	Applies a state to the node and adds nodes of its children
+	A mounted state gets a node when it is met first, the node is mounted under every page of the state
+*/
+func (e *Node) loadState(s *State, states map[string]*State, endpoints map[string]Callback, mounted map[string]*Node) error {
+	e.endpointName = s.Endpoint
+	e.title, e.hidden = s.Label, s.Hidden
+	if s.Caption != "" {
+		e.SetCaptionKey(s.Caption)
+	}
+	for _, id := range s.Children {
+		if node, ok := mounted[id]; ok {
+			e.Mount(node)
+			continue
+		}
+		child := states[id]
+		endpoint, err := e.flow.endpoint(child.Endpoint, endpoints)
+		if err != nil {
+			return errors.Wrap(err, id)
+		}
+		var node *Node
+		if child.Mounted {
+			node = e.flow.NewNode(child.Text, endpoint)
+			mounted[id] = node
+			e.Mount(node)
+		} else {
+			node = e.AddSub(child.Text, endpoint)
+		}
+		node.url, node.query = child.URL, child.Query
+		if err := node.loadState(child, states, endpoints, mounted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	A document of an SCXML state chart
+*/
+type scxml struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/07/scxml scxml"`
+	Version string       `xml:"version,attr"`
+	Name    string       `xml:"name,attr,omitempty"`
+	Initial string       `xml:"initial,attr"`
+	States  []scxmlState `xml:"state"`
+}
+
+type scxmlState struct {
+	Id          string            `xml:"id,attr"`
+	Model       *scxmlModel       `xml:"datamodel,omitempty"`
+	Transitions []scxmlTransition `xml:"transition"`
+}
+
+type scxmlModel struct {
+	Data []scxmlData `xml:"data"`
+}
+
+type scxmlData struct {
+	Id    string `xml:"id,attr"`
+	Value string `xml:",chardata"`
+}
+
+type scxmlTransition struct {
+	Event  string `xml:"event,attr"`
+	Target string `xml:"target,attr"`
+}
+
+/*
+	Converts the machine to an SCXML document
+*/
+func (m *StateMachine) scxml() *scxml {
+	doc := &scxml{Version: "1.0", Name: m.Name, Initial: m.Initial}
+	for _, s := range m.States {
+		state := scxmlState{Id: s.Id}
+		model := &scxmlModel{}
+		for _, data := range []scxmlData{
+			{"text", s.Text}, {"children", strings.Join(s.Children, " ")},
+			{"endpoint", s.Endpoint}, {"url", s.URL}, {"query", s.Query}, {"caption", s.Caption}, {"label", s.Label},
+		} {
+			if data.Value != "" {
+				model.Data = append(model.Data, data)
+			}
+		}
+		if s.Mounted {
+			model.Data = append(model.Data, scxmlData{"mounted", "true"})
+		}
+		if s.Hidden {
+			model.Data = append(model.Data, scxmlData{"hidden", "true"})
+		}
+		if len(model.Data) > 0 {
+			state.Model = model
+		}
+		for _, t := range s.Transitions {
+			state.Transitions = append(state.Transitions, scxmlTransition{Event: t.Result + "." + t.Event, Target: t.Target})
+		}
+		doc.States = append(doc.States, state)
+	}
+	return doc
+}
+
+/*
+	Converts an SCXML document to a machine, unknown data of states is ignored
+*/
+func (doc *scxml) machine() *StateMachine {
+	m := &StateMachine{Name: doc.Name, Initial: doc.Initial}
+	if m.Initial == "" && len(doc.States) > 0 {
+		// the first state is the initial one by default
+		m.Initial = doc.States[0].Id
+	}
+	for _, state := range doc.States {
+		s := &State{Id: state.Id}
+		var model []scxmlData
+		if state.Model != nil {
+			model = state.Model.Data
+		}
+		for _, data := range model {
+			value := strings.TrimSpace(data.Value)
+			switch data.Id {
+			case "text":
+				s.Text = data.Value
+			case "children":
+				s.Children = strings.Fields(value)
+			case "mounted":
+				s.Mounted, _ = strconv.ParseBool(value)
+			case "endpoint":
+				s.Endpoint = value
+			case "url":
+				s.URL = value
+			case "query":
+				s.Query = value
+			case "caption":
+				s.Caption = value
+			case "label":
+				s.Label = value
+			case "hidden":
+				s.Hidden, _ = strconv.ParseBool(value)
+			}
+		}
+		for _, t := range state.Transitions {
+			result, event := t.Event, ""
+			if i := strings.Index(result, "."); i >= 0 {
+				result, event = result[:i], result[i+1:]
+			}
+			s.Transitions = append(s.Transitions, &Transition{Event: event, Result: result, Target: t.Target})
+		}
+		m.States = append(m.States, s)
+	}
+	return m
+}
@@ -0,0 +1,105 @@
+package menu_test
+
+import (
+	"bytes"
+	"go-telegram-flow/menu"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var machineEndpoints = map[string]menu.Callback{"stay": stay}
+
+/*
+	Builds a flow with same texts on a page and a subtree mounted under two pages
+*/
+func addMachine(t *testing.T, flow *menu.Menu) {
+	t.Helper()
+	err := flow.Load(&menu.Definition{Nodes: []*menu.Definition{
+		{Text: "my orders", Nodes: []*menu.Definition{
+			{Text: "refresh", Endpoint: "stay"},
+			{Text: "close", Endpoint: "back"},
+		}},
+		{Text: "settings", Nodes: []*menu.Definition{
+			{Text: "save", Endpoint: "stay"},
+		}},
+		{Text: "settings", Endpoint: "stay"},
+	}}, machineEndpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	help := flow.NewNode("help", nil)
+	help.AddSub("faq", nil).AddURL("site", "https://example.com")
+	flow.GetRoot().Mount(help)
+	flow.Find("settings").Mount(help)
+}
+
+/*
+	Exports the flow and checks that the export is a valid machine
+*/
+func exportMachine(t *testing.T, flow *menu.Menu, format menu.MachineFormat) []byte {
+	t.Helper()
+	if err := flow.StateMachine().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := flow.ExportStateMachine(&b, format); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestStateMachineRoundTrip(t *testing.T) {
+	for name, format := range map[string]menu.MachineFormat{"json": menu.MachineJSON, "scxml": menu.MachineSCXML} {
+		t.Run(name, func(t *testing.T) {
+			flow, _ := newFlow(t, "machine-"+name)
+			addMachine(t, flow)
+			exported := exportMachine(t, flow, format)
+			imported, _ := newFlow(t, "machine-"+name+"-imported")
+			if err := imported.ImportStateMachine(bytes.NewReader(exported), format, machineEndpoints); err != nil {
+				t.Fatal(err)
+			}
+			exportMachine(t, imported, format)
+			if got, want := imported.StateMachine().States, flow.StateMachine().States; !reflect.DeepEqual(got, want) {
+				t.Fatalf("the imported flow exports\n%s\ninstead of\n%s", exportMachine(t, imported, format), exported)
+			}
+			shared := imported.Find("help")
+			if shared == nil || imported.Find("settings/help") != shared {
+				t.Fatal("the mounted subtree is not shared after the import")
+			}
+		})
+	}
+}
+
+func TestStateMachineIds(t *testing.T) {
+	flow, _ := newFlow(t, "machine-ids")
+	addMachine(t, flow)
+	ncname := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	ids := make(map[string]bool)
+	for _, s := range flow.StateMachine().States {
+		if !ncname.MatchString(s.Id) {
+			t.Errorf("state %q is not a valid XML identificator", s.Id)
+		}
+		if ids[s.Id] {
+			t.Errorf("state %q is repeated", s.Id)
+		}
+		ids[s.Id] = true
+	}
+	if len(ids) != 10 {
+		t.Errorf("the machine has %d states instead of 10", len(ids))
+	}
+}
+
+func TestStateMachineInitialIsRoot(t *testing.T) {
+	flow, _ := newFlow(t, "machine-initial")
+	addMachine(t, flow)
+	m := flow.StateMachine()
+	m.Initial = m.States[1].Id
+	if err := m.Validate(); err == nil {
+		t.Fatalf("the machine is valid with the initial state %q below the root", m.Initial)
+	}
+	m.Initial = "missing"
+	if err := m.Validate(); err == nil {
+		t.Fatal("the machine is valid with a missing initial state")
+	}
+}
@@ -0,0 +1,126 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var ErrMediaUnsupported = errors.New("the bot is not able to edit media messages")
+
+/*
+	Bot methods that are required to drive menus attached to media messages
+	*tb.Bot implements them
+*/
+type MediaBot interface {
+	EditCaption(message tb.Editable, caption string, options ...interface{}) (*tb.Message, error)
+	EditMedia(message tb.Editable, media tb.InputMedia, options ...interface{}) (*tb.Message, error)
+}
+
+/*
+	Attaches a photo, a video, an audio or a document to the node's page
+	A menu started at a page with media is sent as a media message,
+	pages without media keep the previous media and only change the caption
+	Media is ignored for menus that were sent as a text message
+	Returns the current node
+*/
+func (e *Node) SetMedia(media tb.InputMedia) *Node {
+	e.media = media
+	return e
+}
+
+/*
+	Get media attached to the node's page
+*/
+func (e *Node) GetMedia() tb.InputMedia {
+	return e.media
+}
+
+/*
+	Sends a new menu message for the page the dialog is on
+	Only internal use is intended
+*/
+func (f *Menu) send(to tb.Recipient, d *Dialog) (*tb.Message, error) {
+	page := d.Position
+	markup := f.keyboard(page.markup(d))
+	f.pace(to)
+	if page.media != nil {
+		if media, ok := withCaption(page.media, f.caption(d)); ok {
+			msg, err := f.bot.Send(to, media, page.options(markup, tb.Silent)...)
+			if err == nil {
+				d.media = page.media
+			}
+			return msg, err
+		}
+	}
+	msg, err := f.bot.Send(to, f.caption(d), page.options(markup, tb.Silent)...)
+	if err == nil {
+		d.media = nil
+	}
+	return msg, err
+}
+
+/*
+	Edits the menu message of the dialog with a specified markup
+	Media messages get their caption and media edited instead of the text
+*/
+func (f *Menu) editMessage(d *Dialog, markup *tb.ReplyMarkup, options ...interface{}) (*tb.Message, error) {
+	caption := f.caption(d)
+	page := d.Position
+	if d.Message.Chat != nil {
+		f.pace(d.Message.Chat)
+	}
+	if f.reply {
+		// a reply keyboard can not be edited, so a new message is sent instead
+		return f.bot.Send(d.Message.Chat, caption, page.options(f.keyboard(markup), options...)...)
+	}
+	if !isMedia(d.Message) {
+		return f.bot.Edit(d.Message, caption, page.options(markup, options...)...)
+	}
+	bot, ok := f.bot.(MediaBot)
+	if !ok {
+		return nil, ErrMediaUnsupported
+	}
+	if page != nil && page.media != nil && page.media != d.media {
+		if media, ok := withCaption(page.media, caption); ok {
+			msg, err := bot.EditMedia(d.Message, media, page.options(markup, options...)...)
+			if err == nil {
+				d.media = page.media
+			}
+			return msg, err
+		}
+	}
+	return bot.EditCaption(d.Message, caption, page.options(markup, options...)...)
+}
+
+/*
+	Checks if a message carries media with a caption
+*/
+func isMedia(m *tb.Message) bool {
+	return m != nil && (m.Photo != nil || m.Video != nil || m.Audio != nil || m.Document != nil)
+}
+
+/*
+	Copies media with a new caption
+	Returns false if the kind of media is not supported
+*/
+func withCaption(media tb.InputMedia, caption string) (tb.InputMedia, bool) {
+	switch m := media.(type) {
+	case *tb.Photo:
+		c := *m
+		c.Caption = caption
+		return &c, true
+	case *tb.Video:
+		c := *m
+		c.Caption = caption
+		return &c, true
+	case *tb.Audio:
+		c := *m
+		c.Caption = caption
+		return &c, true
+	case *tb.Document:
+		c := *m
+		c.Caption = caption
+		return &c, true
+	}
+	return nil, false
+}
@@ -7,36 +7,100 @@ package menu
 */
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/tucnak/tr"
 	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrDialogNotFound = errors.New("dialog not found")
+	ErrUnknownLocale  = errors.New("locale does not exist")
 )
 
 /*
 	A flow is essentially a high-level representation of a menu
 */
 type Menu struct {
-	id            string
-	serial        uint32
-	root          *Node
-	bot           *tb.Bot
-	dialogs       map[string]*Dialog
-	defaultLocale string
-	engine        *tr.Engine
-	mx            sync.RWMutex
-}
-
-/*
-	A dialog is an abstract piece that holds a menu message sent by the bot
-	and a language that the interface is displayed
-*/
-type Dialog struct {
-	Message  *tb.Message
-	Language string
-	Position *Node
+	id              string
+	serial          uint32
+	paths           map[string]int
+	payloads        map[string]string
+	retries         int
+	retryDelay      time.Duration
+	undoLimit       int
+	resume          bool
+	resumePrompt    string
+	resumeNode      *Node
+	detectLanguage  func(user *tb.User) string
+	localeMeta      map[string]LocaleMeta
+	routes          map[string]func(c *tb.Callback)
+	dispatching     bool
+	markupCache     *markupCache
+	numbered        bool
+	cooldowns       cooldowns
+	quota           QuotaChecker
+	upsell          *Node
+	audit           AuditSink
+	root            *Node
+	bot             Bot
+	dialogs         *dialogStore
+	defaultLocale   string
+	locales         []string
+	engine          *tr.Engine
+	prevLabel       string
+	nextLabel       string
+	middlewares     []Middleware
+	breadcrumbs     string
+	onStale         StaleHandler
+	ttl             time.Duration
+	idleAction      IdleAction
+	onExpire        func(d *Dialog)
+	sweeper         chan struct{}
+	disabledPrefix  string
+	disabledAlert   string
+	reply           bool
+	backKey         string
+	homeKey         string
+	onEnter         PageHook
+	onLeave         PageHook
+	onNavigate      NavigationHook
+	onClose         PageHook
+	onError         ErrorHandler
+	resend          bool
+	perMessage      bool
+	groups          bool
+	copyForeign     bool
+	foreignAlert    string
+	ownerLock       bool
+	answered        sync.Map
+	answersSwept    int64
+	respondTimeout  time.Duration
+	lazyLocales     bool
+	metrics         MetricsSink
+	logger          Logger
+	limiter         *limiter
+	queue           *queue
+	debounce        time.Duration
+	ctx             context.Context
+	endpointTimeout time.Duration
+	onShutdown      func(d *Dialog) error
+	shutdownCaption string
+	gate            gate
+	authorize       func(userID int, node *Node) bool
+	invoices        map[string]*Node
+	keepOnClose     bool
+	closeCaption    string
+	err             error
+	variants        map[string]func(*Node) bool
+	progressButton  bool
+	tree            sync.RWMutex
+	mx              sync.RWMutex
 }
 
 /*
@@ -44,18 +108,33 @@ type Dialog struct {
 	Warning! When setting a id treat it gently, like picking a directory name, same rules applies.
 	It will fail without a notice if you put special characters or symbols (except for underscore) in it.
 	Suggested names: flow1, flow_1, MyFlow
+	The bot is usually *tb.Bot, though any implementation of Bot is accepted
 */
-func NewMenuFlow(id string, bot *tb.Bot, engine *tr.Engine) (*Menu, error) {
+func NewMenuFlow(id string, bot Bot, engine *tr.Engine) (*Menu, error) {
 	f := &Menu{
-		id:      id,
-		serial:  0,
-		bot:     bot,
-		dialogs: make(map[string]*Dialog),
-		engine:  engine,
-		mx:      sync.RWMutex{},
+		id:            id,
+		serial:        0,
+		bot:           bot,
+		dialogs:       newDialogStore(),
+		engine:        engine,
+		prevLabel:     "«",
+		nextLabel:     "»",
+		disabledAlert: "This option is not available",
+		foreignAlert:  "This menu is not for you",
+		logger:        stdLogger{},
+		limiter:       newLimiter(),
+		queue:         newQueue(),
+		mx:            sync.RWMutex{},
 	}
 	atomic.StoreUint32(&f.serial, 0)
-	f.root = &Node{id: id + "_root", flow: f, mustUpdate: false, markups: make(map[string]*tb.ReplyMarkup)}
+	f.root = &Node{
+		id:       id + "_root",
+		flow:     f,
+		markups:  make(map[string]*tb.ReplyMarkup),
+		buttons:  make(map[string][]tb.InlineButton),
+		controls: make(map[string]tb.InlineButton),
+		actions:  make(map[string]func(to tb.Recipient, d *Dialog) error),
+	}
 	return f, nil
 }
 
@@ -76,7 +155,7 @@ func (f *Menu) CountNodes() int {
 /*
 	Get attached Telegram bot
 */
-func (f *Menu) GetBot() *tb.Bot {
+func (f *Menu) GetBot() Bot {
 	return f.bot
 }
 
@@ -87,14 +166,66 @@ func (f *Menu) GetRoot() *Node {
 	return f.root
 }
 
+/*
+	Finds a node by a path of texts relative to the root (e.g. "order/pizza")
+	Provided nodes are not a part of the tree, so they are never found
+	Returns nil if there is no such node, an empty path is the root
+*/
+func (f *Menu) Find(path string) *Node {
+	node := f.root
+	for _, text := range strings.Split(path, "/") {
+		if text == "" {
+			continue
+		}
+		var next *Node
+		for _, child := range node.nodes {
+			if child.text == text {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+/*
+	Sets labels of the navigation buttons for paginated pages
+*/
+func (f *Menu) SetPageLabels(prev, next string) *Menu {
+	f.prevLabel = prev
+	f.nextLabel = next
+	return f
+}
+
+/*
+	Sets a prefix for labels of disabled buttons (e.g. "🚫 ") and a text of the alert
+	that is shown when a disabled button is pressed
+*/
+func (f *Menu) SetDisabledStyle(prefix, alert string) *Menu {
+	f.disabledPrefix = prefix
+	f.disabledAlert = alert
+	return f
+}
+
+/*
+	Adds a back button to every page except for the root one, so tree authors do not have to add back nodes
+	The label is a locale key relative to the flow (e.g. "back" is localized by "<flow id>/back")
+	Must be called before the flow is built
+*/
+func (f *Menu) WithAutoBack(label string) *Menu {
+	f.backKey = label
+	return f
+}
+
 /*
 	Retrieves a dialog by user id
 */
 func (f *Menu) GetDialog(id string) (*Dialog, bool) {
-	f.mx.RLock()
-	d, ok := f.dialogs[id]
-	f.mx.RUnlock()
-	return d, ok
+	return f.dialogs.get(id)
 }
 
 /*
@@ -102,9 +233,8 @@ func (f *Menu) GetDialog(id string) (*Dialog, bool) {
 	Only internal use is intended
 */
 func (f *Menu) setDialog(id string, dialog *Dialog) {
-	f.mx.Lock()
-	f.dialogs[id] = dialog
-	f.mx.Unlock()
+	dialog.touch()
+	f.dialogs.set(id, dialog)
 }
 
 /*
@@ -112,14 +242,12 @@ func (f *Menu) setDialog(id string, dialog *Dialog) {
 	Only internal use is intended
 */
 func (f *Menu) deleteDialog(id string) {
-	f.mx.Lock()
-	delete(f.dialogs, id)
-	f.mx.Unlock()
+	f.dialogs.delete(id)
 }
 
 /*
 	Sets a new caption for the menu
-	The caption will be updated right away
+	The caption will be updated right away, or right after a tap that the dialog is handling
 	Params are automatically placed in the text if provided
 */
 func (f *Menu) SetCaption(recipient tb.Recipient, text string, params ...interface{}) *Menu {
@@ -127,9 +255,14 @@ func (f *Menu) SetCaption(recipient tb.Recipient, text string, params ...interfa
 		if len(params) > 0 {
 			text = fmt.Sprintf(text, params...)
 		}
-		if d.Message.Text != text {
-			d.Message.Text = text
-			d.Position.update(recipient, d, d.Position.GetMarkup(d.Language))
+		err := f.inTurn(d, func() error {
+			if d.setText(text) {
+				return d.Position.update(recipient, d, d.Position)
+			}
+			return nil
+		})
+		if err != nil {
+			f.fail(err, nil, d.Position)
 		}
 	}
 	return f
@@ -166,76 +299,273 @@ func (f *Menu) NewBackNode(text string) *Node {
 
 /*
 	Builds the flow for a specified locale
+	Problems of the tree are logged and can be retrieved with Err
 */
 func (f *Menu) Build(lang string) *Menu {
-	f.root.build(f.id, lang)
+	f.tree.Lock()
+	f.build(lang)
+	f.tree.Unlock()
 	return f
 }
 
 /*
-	Sends a new instance of a menu to a user with a specified locale
-	Tries to delete the old menu before sending a new one
+	Builds the flow for a locale of the engine unless it is built already
+	It is safe to call while the bot is running
+	Returns *ValidationError if the tree has problems in the locale (see Validate)
 */
-func (f *Menu) Start(to tb.Recipient, text, lang string) error {
-	if d, ok := f.GetDialog(to.Recipient()); ok {
-		f.bot.Delete(d.Message)
+func (f *Menu) BuildLocale(lang string) error {
+	if _, ok := f.engine.Langs[lang]; !ok {
+		return ErrUnknownLocale
 	}
-	msg, err := f.bot.Send(to, text, f.root.markups[lang], tb.Silent)
-	if err != nil {
-		return err
+	f.tree.Lock()
+	defer f.tree.Unlock()
+	if !f.isBuilt(lang) {
+		return f.build(lang)
 	}
-	f.setDialog(to.Recipient(), &Dialog{Message: msg, Language: lang, Position: f.root})
 	return nil
 }
 
+/*
+	Makes the flow build a locale of the engine the first time a dialog uses it,
+	so locales do not have to be built upfront
+*/
+func (f *Menu) WithLazyLocales() *Menu {
+	f.lazyLocales = true
+	return f
+}
+
+/*
+	Validates the tree, builds its markups and registers the locale
+	A tree with a cycle is not built
+	The tree has to be locked by the caller
+*/
+func (f *Menu) build(lang string) error {
+	problems := &ValidationError{}
+	f.validate(lang, problems)
+	var err error
+	if len(problems.Problems) > 0 {
+		err = problems
+		f.logger.Error("invalid menu tree", Fields{"locale": lang, "error": err})
+	}
+	f.mx.Lock()
+	f.err = err
+	f.mx.Unlock()
+	if problems.cyclic {
+		return err
+	}
+	if f.markupCache != nil {
+		f.markupCache.clear()
+	}
+	f.root.build(f.id, lang)
+	for name, filter := range f.variants {
+		f.root.buildVariant(name, lang, filter)
+	}
+	f.mx.Lock()
+	if !f.hasLocale(lang) {
+		f.locales = append(f.locales, lang)
+	}
+	if f.defaultLocale == "" {
+		f.defaultLocale = lang
+	}
+	f.mx.Unlock()
+	return err
+}
+
+/*
+	Checks if the flow was built for a locale
+*/
+func (f *Menu) isBuilt(lang string) bool {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	return f.hasLocale(lang)
+}
+
+/*
+	Checks if a locale is in the list of built locales
+	The flow has to be locked by the caller
+*/
+func (f *Menu) hasLocale(lang string) bool {
+	for _, built := range f.locales {
+		if built == lang {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Finds a built locale for a language, so an unknown language never leaves a menu without buttons
+	A regional language falls back to its base language (e.g. "de-AT" to "de") and then to the default locale
+	Only internal use is intended
+*/
+func (f *Menu) resolve(lang string) string {
+	for candidate := lang; candidate != ""; candidate = baseLocale(candidate) {
+		if f.isBuilt(candidate) {
+			return candidate
+		}
+		if f.lazyLocales && f.BuildLocale(candidate) != ErrUnknownLocale && f.isBuilt(candidate) {
+			return candidate
+		}
+	}
+	return f.GetDefaultLocale()
+}
+
+/*
+	Cuts the last subtag of a language tag (e.g. "de-AT" becomes "de")
+	Returns an empty string for a language without subtags
+*/
+func baseLocale(lang string) string {
+	if i := strings.LastIndexAny(lang, "-_"); i > 0 {
+		return lang[:i]
+	}
+	return ""
+}
+
+/*
+	Sets a locale that is used when no locale is specified for a dialog
+	By default the first built locale is used
+*/
+func (f *Menu) SetDefaultLocale(lang string) *Menu {
+	f.mx.Lock()
+	f.defaultLocale = lang
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Gets the locale that is used when no locale is specified for a dialog
+*/
+func (f *Menu) GetDefaultLocale() string {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	return f.defaultLocale
+}
+
+/*
+	Gets all locales that the flow was built for
+*/
+func (f *Menu) GetLocales() []string {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	return append([]string(nil), f.locales...)
+}
+
+/*
+	Sends a new instance of a menu to a user with a specified locale
+	An empty locale is detected from the profile of the user (see WithLanguageDetector)
+	Tries to delete the old menu before sending a new one, unless every message holds a dialog of its own
+*/
+func (f *Menu) Start(to tb.Recipient, text, lang string) error {
+	return f.start(to, to.Recipient(), ownerOf(to), text, lang, f.root, true)
+}
+
 /*
 	Sends an instance of a menu to a user starting at a specified node
-	Tries to delete the old menu before sending a new one
+	Tries to delete the old menu before sending a new one, unless every message holds a dialog of its own
 */
 func (f *Menu) StartAt(to tb.Recipient, text, lang string, at *Node) error {
-	d, ok := f.GetDialog(to.Recipient())
-	if ok {
+	return f.start(to, to.Recipient(), ownerOf(to), text, lang, at, false)
+}
+
+/*
+	Sends a menu at a specified node and stores the dialog by a specified id
+	A fresh dialog drops the values of the previous one
+	A menu without an owner is refused while the owner lock is on
+*/
+func (f *Menu) start(to tb.Recipient, id, owner, text, lang string, at *Node, fresh bool) error {
+	if f.ownerLock && owner == "" {
+		return ErrNoOwner
+	}
+	var from *Node
+	d, ok := f.GetDialog(id)
+	if ok && !f.perMessage {
 		f.bot.Delete(d.Message)
-	} else {
+		if !fresh {
+			from = d.Position
+		}
+	}
+	if ok && fresh && !f.perMessage {
+		d.close()
+	}
+	if !ok || fresh || f.perMessage {
 		d = &Dialog{}
 	}
-	msg, err := f.bot.Send(to, text, at.markups[lang], tb.Silent)
-	if err != nil {
-		return err
+	if lang == "" {
+		lang = f.languageFor(to)
 	}
-	d.Message = msg
+	d.owner = owner
+	d.history = nil
 	d.Language = lang
 	d.Position = at
-	f.setDialog(to.Recipient(), d)
+	d.setText(text)
+	msg, err := f.send(to, d)
+	if err != nil {
+		return err
+	}
+	f.setMessage(d, msg)
+	f.setDialog(id, d)
+	f.navigated(from, at, d)
 	return nil
 }
 
+/*
+	Sends an instance of a menu to any chat at a specified node in the language of the user
+	or in the default locale for other chats,
+	so that the flow can be started proactively (e.g. by a scheduled job or an admin command)
+	The caption is localized by the path of the node, the root is used if the node is nil
+*/
+func (f *Menu) SendTo(recipient tb.Recipient, startNode *Node) error {
+	if startNode == nil {
+		startNode = f.root
+	}
+	lang := f.languageFor(recipient)
+	return f.StartAt(recipient, f.engine.Lang(lang).Tr(startNode.path), lang, startNode)
+}
+
 /*
 	Takes a user to a specified menu position (page)
+	A dialog that is handling a tap is moved right after the tap,
+	the error of such a move is passed to the error handler of the flow
 */
 func (f *Menu) MoveTo(to tb.Recipient, text, lang string, position *Node) error {
 	d, ok := f.GetDialog(to.Recipient())
 	if !ok {
-		return errors.New("dialog not found")
+		return ErrDialogNotFound
 	}
-	msg, err := f.bot.Edit(d.Message, text, position.markups[lang], tb.Silent)
+	return f.inTurn(d, func() error {
+		return f.moveTo(to, d, text, lang, position)
+	})
+}
+
+/*
+	Moves a dialog to a specified menu position
+*/
+func (f *Menu) moveTo(to tb.Recipient, d *Dialog, text, lang string, position *Node) error {
+	prevLang, prevPosition, prevText, prevKey := d.Language, d.Position, d.text, d.textKey
+	d.Language = lang
+	d.Position = position
+	d.setText(text)
+	msg, err := f.edit(d, position.markup(d), tb.Silent)
 	if err != nil {
+		d.Language, d.Position, d.text, d.textKey = prevLang, prevPosition, prevText, prevKey
 		return err
 	}
-	d.Message = msg
-	d.Language = lang
-	d.Position = position
+	f.setMessage(d, msg)
+	d.record(prevPosition, position)
 	f.setDialog(to.Recipient(), d)
+	f.navigated(prevPosition, position, d)
 	return nil
 }
 
 /*
-	Removes the menu from a user and deletes the session
+	Removes the menu from a user and deletes the session the same way as Close
 */
 func (f *Menu) Stop(to tb.Recipient, text, lang string) error {
-	if d, ok := f.GetDialog(to.Recipient()); ok {
-		f.bot.Delete(d.Message)
+	d, ok := f.GetDialog(to.Recipient())
+	if !ok {
+		return nil
 	}
-	f.deleteDialog(to.Recipient())
-	return nil
+	return f.inTurn(d, func() error {
+		return f.close(to.Recipient(), d)
+	})
 }
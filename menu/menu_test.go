@@ -0,0 +1,59 @@
+package menu_test
+
+import (
+	"fmt"
+	"go-telegram-flow/menu"
+	"go-telegram-flow/menu/menutest"
+	"sync"
+	"testing"
+
+	"github.com/tucnak/tr"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var engineOnce sync.Once
+
+/*
+	Creates a flow driven by a fake bot with the test locales
+*/
+func newFlow(t testing.TB, id string) (*menu.Menu, *menutest.Bot) {
+	t.Helper()
+	bot := menutest.NewBot()
+	return newFlowOn(t, id, bot), bot
+}
+
+/*
+	Creates a flow driven by a specified bot with the test locales
+*/
+func newFlowOn(t testing.TB, id string, bot menu.Bot) *menu.Menu {
+	t.Helper()
+	engineOnce.Do(func() {
+		if err := tr.Init("testdata/lang", "en"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	flow, err := menu.NewMenuFlow(id, bot, tr.DefaultEngine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return flow
+}
+
+/*
+	An endpoint that leaves the dialog on its page
+*/
+func stay(e *menu.Node, c *tb.Callback) int {
+	return menu.Stay
+}
+
+/*
+	Adds a paginated page with a number of items to the root
+*/
+func addList(flow *menu.Menu, items, pageSize int) *menu.Node {
+	list := flow.GetRoot().AddSub("list", nil)
+	for i := 0; i < items; i++ {
+		list.Add(fmt.Sprint("item", i), stay)
+	}
+	list.SetPageSize(pageSize)
+	return list
+}
@@ -0,0 +1,315 @@
+package menutest
+
+/*
+	A fake bot that allows to test flows without Telegram
+	Author: Daniil Furmanov
+	License: MIT
+*/
+
+import (
+	"github.com/pkg/errors"
+	"go-telegram-flow/menu"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrNodeNotFound    = errors.New("the node is not found by the path")
+	ErrButtonNotFound  = errors.New("the button is not displayed to the user")
+	ErrHandlerNotFound = errors.New("no handler is registered for the button")
+)
+
+/*
+	A call of a bot method that sends or edits a message
+*/
+type Call struct {
+	Message *tb.Message
+	What    interface{}
+	Markup  *tb.ReplyMarkup
+}
+
+/*
+	An answer to a pre-checkout query, the error message is empty when the order was accepted
+*/
+type Checkout struct {
+	Query        *tb.PreCheckoutQuery
+	ErrorMessage string
+}
+
+/*
+	Bot is a fake implementation of menu.Bot, menu.MediaBot, menu.InlineBot and menu.PaymentBot
+	Sent and edited messages are recorded instead of being delivered,
+	buttons of the last message in a chat can be pressed with Bot.Tap and Bot.Press, commands are sent with Bot.Command
+*/
+type Bot struct {
+	Sends     []Call
+	Edits     []Call
+	Deletes   []tb.Editable
+	Responses []*tb.CallbackResponse
+	Answers   []*tb.QueryResponse
+	Checkouts []Checkout
+	handlers  map[string]interface{}
+	last      map[int64]*tb.Message
+	serial    int
+	callbacks int
+	mx        sync.Mutex
+}
+
+/*
+	Creates a new fake bot
+*/
+func NewBot() *Bot {
+	return &Bot{
+		handlers: make(map[string]interface{}),
+		last:     make(map[int64]*tb.Message),
+	}
+}
+
+/*
+	Registers a handler of an endpoint
+*/
+func (b *Bot) Handle(endpoint interface{}, handler interface{}) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.handlers[key(endpoint)] = handler
+}
+
+/*
+	Records a new message to a recipient
+	The message gets a new id and becomes the last message of the chat
+*/
+func (b *Bot) Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error) {
+	chat, err := strconv.ParseInt(to.Recipient(), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.serial++
+	msg := message(b.serial, chat, what, markup(options))
+	b.Sends = append(b.Sends, Call{Message: msg, What: what, Markup: markup(options)})
+	b.last[chat] = msg
+	return msg, nil
+}
+
+/*
+	Records an edit of a message
+*/
+func (b *Bot) Edit(m tb.Editable, what interface{}, options ...interface{}) (*tb.Message, error) {
+	return b.edit(m, what, options)
+}
+
+/*
+	Records an edit of a caption of a media message
+*/
+func (b *Bot) EditCaption(m tb.Editable, caption string, options ...interface{}) (*tb.Message, error) {
+	return b.edit(m, caption, options)
+}
+
+/*
+	Records a replacement of media of a message
+*/
+func (b *Bot) EditMedia(m tb.Editable, media tb.InputMedia, options ...interface{}) (*tb.Message, error) {
+	return b.edit(m, media, options)
+}
+
+/*
+	Records a deletion of a message
+*/
+func (b *Bot) Delete(m tb.Editable) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.Deletes = append(b.Deletes, m)
+	_, chat := m.MessageSig()
+	delete(b.last, chat)
+	return nil
+}
+
+/*
+	Records an answer to a callback
+*/
+func (b *Bot) Respond(c *tb.Callback, response ...*tb.CallbackResponse) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if len(response) > 0 {
+		b.Responses = append(b.Responses, response[0])
+	} else {
+		b.Responses = append(b.Responses, &tb.CallbackResponse{CallbackID: c.ID})
+	}
+	return nil
+}
+
+/*
+	Records an answer to an inline query
+*/
+func (b *Bot) Answer(q *tb.Query, resp *tb.QueryResponse) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.Answers = append(b.Answers, resp)
+	return nil
+}
+
+/*
+	Records an answer to a pre-checkout query
+*/
+func (b *Bot) Accept(q *tb.PreCheckoutQuery, errorMessage ...string) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	checkout := Checkout{Query: q}
+	if len(errorMessage) > 0 {
+		checkout.ErrorMessage = errorMessage[0]
+	}
+	b.Checkouts = append(b.Checkouts, checkout)
+	return nil
+}
+
+/*
+	Get the last message in a private chat with a user
+	Returns nil if nothing was sent to the user
+*/
+func (b *Bot) Last(user *tb.User) *tb.Message {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.last[int64(user.ID)]
+}
+
+/*
+	Simulates a press of a node's button by a user
+	The node is found by a path of the node texts from the root (see Menu.Find),
+	the button must be displayed in the last message of the user's chat
+*/
+func (b *Bot) Tap(flow *menu.Menu, user *tb.User, path string) error {
+	node := flow.Find(path)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	return b.press(user, func(btn tb.InlineButton) bool {
+		return node.IsButton(btn)
+	})
+}
+
+/*
+	Simulates a press of a button with a specified text by a user
+	Unlike Bot.Tap it reaches auxiliary buttons, such as back or page navigation
+*/
+func (b *Bot) Press(user *tb.User, text string) error {
+	return b.press(user, func(btn tb.InlineButton) bool {
+		return btn.Text == text
+	})
+}
+
+/*
+	Simulates a command (e.g. "/start") sent by a user in the private chat with the bot
+*/
+func (b *Bot) Command(user *tb.User, command string) error {
+	b.mx.Lock()
+	handler := b.handlers[command]
+	b.mx.Unlock()
+	h, ok := handler.(func(*tb.Message))
+	if !ok {
+		return ErrHandlerNotFound
+	}
+	h(&tb.Message{Sender: user, Chat: &tb.Chat{ID: int64(user.ID)}, Text: command})
+	return nil
+}
+
+/*
+	Finds a button on the last message of a user's chat and calls its handler with a new callback
+*/
+func (b *Bot) press(user *tb.User, match func(btn tb.InlineButton) bool) error {
+	b.mx.Lock()
+	msg := b.last[int64(user.ID)]
+	var handler interface{}
+	var data string
+	found := false
+	if msg != nil {
+		for _, row := range msg.ReplyMarkup.InlineKeyboard {
+			for _, btn := range row {
+				if btn.Unique != "" && match(btn) {
+					handler, data, found = b.handlers["\f"+btn.Unique], btn.Data, true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+	}
+	b.callbacks++
+	id := strconv.Itoa(b.callbacks)
+	b.mx.Unlock()
+	if !found {
+		return ErrButtonNotFound
+	}
+	h, ok := handler.(func(*tb.Callback))
+	if !ok {
+		return ErrHandlerNotFound
+	}
+	// the handler is called without the lock, since it calls the bot back
+	h(&tb.Callback{ID: id, Sender: user, Message: msg, Data: data})
+	return nil
+}
+
+/*
+	Records an edit of a message and replaces the last message of the chat
+*/
+func (b *Bot) edit(m tb.Editable, what interface{}, options []interface{}) (*tb.Message, error) {
+	id, chat := m.MessageSig()
+	serial, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	msg := message(serial, chat, what, markup(options))
+	b.Edits = append(b.Edits, Call{Message: msg, What: what, Markup: markup(options)})
+	b.last[chat] = msg
+	return msg, nil
+}
+
+/*
+	Creates a message that the fake bot returns
+*/
+func message(id int, chat int64, what interface{}, markup *tb.ReplyMarkup) *tb.Message {
+	msg := &tb.Message{ID: id, Chat: &tb.Chat{ID: chat}}
+	if text, ok := what.(string); ok {
+		msg.Text = text
+	}
+	if markup != nil {
+		msg.ReplyMarkup = tb.InlineKeyboardMarkup{InlineKeyboard: markup.InlineKeyboard}
+	}
+	return msg
+}
+
+/*
+	Finds a markup among options of a bot method
+*/
+func markup(options []interface{}) *tb.ReplyMarkup {
+	for _, option := range options {
+		switch o := option.(type) {
+		case *tb.ReplyMarkup:
+			return o
+		case *tb.SendOptions:
+			if o.ReplyMarkup != nil {
+				return o.ReplyMarkup
+			}
+		}
+	}
+	return nil
+}
+
+/*
+	Gets a key of an endpoint the same way as telebot does
+*/
+func key(endpoint interface{}) string {
+	switch e := endpoint.(type) {
+	case string:
+		return e
+	case *tb.InlineButton:
+		return "\f" + e.Unique
+	case *tb.ReplyButton:
+		return e.Text
+	}
+	return ""
+}
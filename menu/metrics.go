@@ -0,0 +1,52 @@
+package menu
+
+import (
+	"time"
+)
+
+/*
+	A receiver of usage events of a menu (e.g. an exporter of Prometheus counters and histograms)
+	Displayed is called for every page shown to a user, Pressed for every accepted button press
+	and Edited for every edit of a menu message with its latency and a failure if any
+	Methods are called synchronously, so they should not block
+*/
+type MetricsSink interface {
+	Displayed(node *Node)
+	Pressed(node *Node)
+	Edited(latency time.Duration, err error)
+}
+
+/*
+	Sets a receiver of usage events of the menu
+*/
+func (f *Menu) WithMetrics(m MetricsSink) *Menu {
+	f.metrics = m
+	return f
+}
+
+/*
+	Reports a displayed page
+*/
+func (f *Menu) reportDisplayed(node *Node) {
+	if f.metrics != nil {
+		f.metrics.Displayed(node)
+	}
+}
+
+/*
+	Reports a pressed button
+*/
+func (f *Menu) reportPressed(node *Node) {
+	if f.metrics != nil {
+		f.metrics.Pressed(node)
+	}
+}
+
+/*
+	Reports an edit of a menu message that has started at a specified moment
+*/
+func (f *Menu) reportEdited(started time.Time, err error) {
+	if f.metrics != nil {
+		f.metrics.Edited(time.Since(started), err)
+	}
+}
@@ -0,0 +1,26 @@
+package menu
+
+/*
+	A middleware wraps node endpoints to add cross-cutting behaviour (auth checks, logging, metrics)
+	It may call the next callback or return a result on its own
+*/
+type Middleware func(next Callback) Callback
+
+/*
+	Adds middlewares that wrap every node endpoint of the flow
+	Middlewares are applied in the order they were added, the first one is the outermost
+*/
+func (f *Menu) Use(middlewares ...Middleware) *Menu {
+	f.middlewares = append(f.middlewares, middlewares...)
+	return f
+}
+
+/*
+	Wraps an endpoint with all middlewares of the flow
+*/
+func (f *Menu) wrap(endpoint Callback) Callback {
+	for i := len(f.middlewares) - 1; i >= 0; i-- {
+		endpoint = f.middlewares[i](endpoint)
+	}
+	return endpoint
+}
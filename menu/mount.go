@@ -0,0 +1,54 @@
+package menu
+
+/*
+	Mounts a shared subtree under the current node, the same node can be mounted under many parents
+	(e.g. a "Help" section), the subtree is localized at the top level of the flow by "<flow id>/<text>/..."
+	Back buttons and breadcrumbs of the subtree follow the parent the dialog came from
+	Returns the current node
+*/
+func (e *Node) Mount(shared *Node) *Node {
+	shared.mounted = true
+	if shared.prev == nil {
+		shared.prev = e.flow.root
+	}
+	e.nodes = append(e.nodes, shared)
+	return e
+}
+
+/*
+	Gets the parent of the node that a dialog has reached it from
+	A mounted node returns the last visited page it is mounted under, other nodes return their own parent
+*/
+func (e *Node) parent(d *Dialog) *Node {
+	if d == nil {
+		return e.prev
+	}
+	return e.parentIn(d.history)
+}
+
+/*
+	Gets the parent of the node among visited pages, the last one that a mounted node is mounted under
+*/
+func (e *Node) parentIn(history []*Node) *Node {
+	if !e.mounted {
+		return e.prev
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		for _, child := range history[i].nodes {
+			if child == e {
+				return history[i]
+			}
+		}
+	}
+	return e.prev
+}
+
+/*
+	Gets the base path of the node's children, mounted nodes are localized from the top of the flow
+*/
+func (e *Node) childPath(child *Node, path string) string {
+	if child.mounted {
+		return e.flow.id
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package menu
+
+/*
+	Removes a child of the node along with its subtree while the bot is running
+	The flow is rebuilt in every built locale, buttons of the removed nodes on old messages are treated as stale
+	and dialogs on the removed pages are moved to the node's page
+	Returns the current node
+*/
+func (e *Node) Remove(child *Node) *Node {
+	e.flow.tree.Lock()
+	defer e.flow.tree.Unlock()
+	for i, node := range e.nodes {
+		if node == child {
+			e.nodes = append(e.nodes[:i:i], e.nodes[i+1:]...)
+			e.flow.detach(e, child)
+			break
+		}
+	}
+	e.flow.rebuild()
+	return e
+}
+
+/*
+	Inserts new children at a position among the node's children while the bot is running
+	A position out of range appends the children, the flow is rebuilt in every built locale
+	Returns the current node
+*/
+func (e *Node) InsertAt(i int, elements ...*Node) *Node {
+	e.flow.tree.Lock()
+	defer e.flow.tree.Unlock()
+	if i < 0 || i > len(e.nodes) {
+		i = len(e.nodes)
+	}
+	for _, el := range elements {
+		el.prev = e
+	}
+	nodes := make([]*Node, 0, len(e.nodes)+len(elements))
+	nodes = append(nodes, e.nodes[:i]...)
+	nodes = append(nodes, elements...)
+	e.nodes = append(nodes, e.nodes[i:]...)
+	e.flow.rebuild()
+	return e
+}
+
+/*
+	Replaces a child of the node with a new one at the same position while the bot is running
+	The old subtree is removed the same way as by Remove
+	Returns the current node
+*/
+func (e *Node) Replace(old, replacement *Node) *Node {
+	e.flow.tree.Lock()
+	defer e.flow.tree.Unlock()
+	for i, node := range e.nodes {
+		if node == old {
+			replacement.prev = e
+			e.nodes[i] = replacement
+			e.flow.detach(e, old)
+			break
+		}
+	}
+	e.flow.rebuild()
+	return e
+}
+
+/*
+	Moves a child of the node by a number of positions among its siblings while the bot is running
+	Returns false if the child can not be moved that far
+*/
+func (e *Node) move(child *Node, delta int) bool {
+	e.flow.tree.Lock()
+	defer e.flow.tree.Unlock()
+	for i, node := range e.nodes {
+		if node != child {
+			continue
+		}
+		j := i + delta
+		if j < 0 || j >= len(e.nodes) {
+			return false
+		}
+		e.nodes[i], e.nodes[j] = e.nodes[j], e.nodes[i]
+		e.flow.rebuild()
+		return true
+	}
+	return false
+}
+
+/*
+	Unregisters handlers of a removed subtree and moves dialogs from its pages to the parent's page,
+	dialogs stop awaiting inputs for its nodes
+	Nodes of the subtree that are still reachable from the root are kept
+	The tree has to be locked by the caller
+*/
+func (f *Menu) detach(parent, removed *Node) {
+	live := make(map[*Node]bool)
+	f.root.walk(func(e *Node) {
+		live[e] = true
+	})
+	nodes := make(map[*Node]bool)
+	removed.walk(func(e *Node) {
+		// shared subtrees may still be mounted elsewhere
+		if !live[e] {
+			nodes[e] = true
+		}
+	})
+	f.mx.Lock()
+	for e := range nodes {
+		delete(f.routes, e.id)
+		for action := range e.controls {
+			delete(f.routes, e.controlRoute(action))
+		}
+	}
+	f.mx.Unlock()
+	f.dialogs.each(func(id string, d *Dialog) {
+		d.mx.Lock()
+		defer d.mx.Unlock()
+		if nodes[d.Position] {
+			d.Position = parent
+			d.dirty = true
+		}
+		if nodes[d.input] {
+			// the removed node can not handle the awaited input
+			d.cancelInput()
+			d.dirty = true
+		}
+		history := d.history[:0]
+		for _, page := range d.history {
+			if !nodes[page] {
+				history = append(history, page)
+			}
+		}
+		d.history = history
+	})
+}
+
+/*
+	Builds the flow anew in every built locale after the tree has changed
+	The tree has to be locked by the caller
+*/
+func (f *Menu) rebuild() {
+	for _, lang := range f.GetLocales() {
+		f.build(lang)
+	}
+}
@@ -0,0 +1,36 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestRemoveStopsAwaitingInput(t *testing.T) {
+	flow, bot := newFlow(t, "mutate")
+	root := flow.GetRoot()
+	form := root.AddSub("form", nil).Add("name", nil)
+	handled := false
+	flow.Find("form/name").AwaitText("your name", func(e *menu.Node, m *tb.Message) int {
+		handled = true
+		return menu.Stay
+	})
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "mutate", "en", root); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"form", "form/name"} {
+		if err := bot.Tap(flow, user, path); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	root.Remove(form)
+	if flow.Process(&tb.Message{Sender: user, Chat: &tb.Chat{ID: int64(user.ID)}, Text: "Ann"}) || handled {
+		t.Fatal("the input is handled by a removed node")
+	}
+	if d, ok := flow.GetDialog(user.Recipient()); !ok || d.Position != root {
+		t.Fatal("the dialog is not moved off the removed page")
+	}
+}
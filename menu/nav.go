@@ -0,0 +1,139 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Callback function declaration for endpoints that return a navigation result instead of Stay, Forward or Back
+*/
+type NavCallback func(e *Node, c *tb.Callback) NavigationResult
+
+/*
+	An outcome of an endpoint: where the dialog goes and what the user is told
+	Results are combined by chaining, e.g. Nav().Alert("Saved").Goto("orders")
+*/
+type NavigationResult struct {
+	result int
+	path   string
+	node   *Node
+	goTo   bool
+	close  bool
+	alert  string
+}
+
+/*
+	Creates a result that leaves the dialog on its page
+*/
+func Nav() NavigationResult {
+	return NavigationResult{result: Stay}
+}
+
+/*
+	Leaves the dialog on its page
+*/
+func (r NavigationResult) Stay() NavigationResult {
+	r.result, r.goTo = Stay, false
+	return r
+}
+
+/*
+	Continues to the node's page the same way as returning Forward
+*/
+func (r NavigationResult) Forward() NavigationResult {
+	r.result, r.goTo = Forward, false
+	return r
+}
+
+/*
+	Goes back the same way as returning Back
+*/
+func (r NavigationResult) Back() NavigationResult {
+	r.result, r.goTo = Back, false
+	return r
+}
+
+/*
+	Takes the dialog to the page of a node found by a path relative to the root (see Menu.Find)
+	A node without a page is opened at the page of its parent
+*/
+func (r NavigationResult) Goto(path string) NavigationResult {
+	r.path, r.node, r.goTo = path, nil, true
+	return r
+}
+
+/*
+	Takes the dialog to the page of a node the same way as Goto
+*/
+func (r NavigationResult) GotoNode(node *Node) NavigationResult {
+	r.node, r.goTo = node, true
+	return r
+}
+
+/*
+	Closes the menu the same way as Node.Close, the navigation is ignored
+*/
+func (r NavigationResult) Close() NavigationResult {
+	r.close = true
+	return r
+}
+
+/*
+	Answers the callback with an alert, the text is localized the same way as for Node.Alert
+*/
+func (r NavigationResult) Alert(text string) NavigationResult {
+	r.alert = text
+	return r
+}
+
+/*
+	Converts an endpoint that returns a navigation result to a regular one,
+	so it can be passed to Add, AddSub and others
+*/
+func Navigating(endpoint NavCallback) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		return endpoint(e, c).apply(e, c)
+	}
+}
+
+/*
+	Carries out the parts of the result that can not be expressed by Stay, Forward or Back
+	Returns the result for the flow to navigate by
+*/
+func (r NavigationResult) apply(e *Node, c *tb.Callback) int {
+	if r.alert != "" {
+		if err := e.Alert(c, r.alert); err != nil {
+			e.flow.fail(err, c, e)
+		}
+	}
+	if r.close {
+		if err := e.Close(c); err != nil {
+			e.flow.fail(err, c, e)
+		}
+		return Stay
+	}
+	if !r.goTo {
+		return r.result
+	}
+	target := r.node
+	if target == nil {
+		target = e.flow.Find(r.path)
+	}
+	if target == nil {
+		e.flow.fail(errors.Wrap(ErrUnknownPath, r.path), c, e)
+		return Stay
+	}
+	if !target.HasPage() && target.prev != nil {
+		target = target.prev
+	}
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		e.flow.fail(ErrDialogNotFound, c, e)
+		return Stay
+	}
+	if err := e.update(c.Sender, d, target); err != nil {
+		e.flow.fail(err, c, e)
+	}
+	return Stay
+}
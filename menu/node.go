@@ -3,8 +3,6 @@ package menu
 import (
 	"fmt"
 	tb "gopkg.in/tucnak/telebot.v2"
-	"log"
-	"strconv"
 	"sync/atomic"
 	"time"
 )
@@ -26,31 +24,74 @@ const (
 	a.k.a a button that holds other buttons for the next page
 */
 type Node struct {
-	id         string
-	flow       *Menu
-	path       string
-	text       string
-	endpoint   Callback
-	markups    map[string]*tb.ReplyMarkup
-	prev       *Node
-	nodes      []*Node
-	mustUpdate bool
+	id           string
+	flow         *Menu
+	path         string
+	text         string
+	labelKey     string
+	raw          bool
+	endpoint     Callback
+	markups      map[string]*tb.ReplyMarkup
+	buttons      map[string][]tb.InlineButton
+	controls     map[string]tb.InlineButton
+	actions      map[string]func(to tb.Recipient, d *Dialog) error
+	prev         *Node
+	nodes        []*Node
+	provider     NodeProvider
+	pageSize     int
+	layout       LayoutFunc
+	input        *textInput
+	visible      func(e *Node, d *Dialog) bool
+	marker       func(d *Dialog) string
+	labelFunc    func(d *Dialog, lang string) string
+	trArgs       func(d *Dialog) []interface{}
+	respondIn    time.Duration
+	url          string
+	query        string
+	captionKey   string
+	media        tb.InputMedia
+	payment      *payment
+	data         string
+	mounted      bool
+	guard        func(e *Node, c *tb.Callback) bool
+	guardAlert   string
+	refreshEvery time.Duration
+	refresher    func(e *Node, d *Dialog)
+	sendOptions  *tb.SendOptions
+	experiment   *experiment
+	variant      string
+	title        string
+	hidden       bool
+	columns      int
+	endpointName string
+	less         func(a, b *Node) bool
+	order        func(d *Dialog, a, b *Node) bool
+	search       bool
+	cooldown     time.Duration
 }
 
 /*
 	Creates a new node in the flow
+	The id is derived from the path of the node, so the uniques of a tree built the same way
+	are the same after a restart and on every instance of the bot
 */
 func newNode(root *Menu, text string, endpoint Callback, prev *Node) *Node {
-	id := atomic.AddUint32(&root.serial, 1)
+	atomic.AddUint32(&root.serial, 1)
+	path := text
+	if prev != nil {
+		path = prev.relativePath() + "/" + text
+	}
 	return &Node{
-		id:         strconv.Itoa(int(id)),
-		flow:       root,
-		text:       text,
-		path:       text,
-		endpoint:   endpoint,
-		prev:       prev,
-		markups:    make(map[string]*tb.ReplyMarkup),
-		mustUpdate: false,
+		id:       root.nodeId(path),
+		flow:     root,
+		text:     text,
+		path:     text,
+		endpoint: endpoint,
+		prev:     prev,
+		markups:  make(map[string]*tb.ReplyMarkup),
+		buttons:  make(map[string][]tb.InlineButton),
+		controls: make(map[string]tb.InlineButton),
+		actions:  make(map[string]func(to tb.Recipient, d *Dialog) error),
 	}
 }
 
@@ -68,6 +109,14 @@ func (e *Node) GetId() string {
 	return e.id
 }
 
+/*
+	Get a unique identificator of the node's button within the bot (e.g. for invoice payloads)
+	Callbacks are dispatched by the node's id, use IsButton to find the node's button in a markup
+*/
+func (e *Node) GetUnique() string {
+	return e.flow.id + uniquePrefix + e.id
+}
+
 /*
 	Get node's default text
 */
@@ -103,12 +152,24 @@ func (e *Node) GetNodes() []*Node {
 	return e.nodes
 }
 
+/*
+	Checks whether the node has a page of its own
+	that is made of either static or provided children
+*/
+func (e *Node) HasPage() bool {
+	return e.provider != nil || len(e.nodes) > 0
+}
+
 /*
 	Get a markups in a specified language
-	Caution! Menu must be built for the specified language beforehand
+	A related built locale is used if the menu was not built for the language (see Menu.Build)
 */
 func (e *Node) GetMarkup(lang string) *tb.ReplyMarkup {
-	return e.markups[lang]
+	lang = e.flow.resolve(lang)
+	e.flow.tree.RLock()
+	defer e.flow.tree.RUnlock()
+	_, markup := e.materialized(lang)
+	return markup
 }
 
 /*
@@ -145,6 +206,52 @@ func (e *Node) AddSub(text string, endpoint Callback) *Node {
 	return newElement
 }
 
+/*
+	Adds a new sub node with data attached to its button
+	The data is passed to the endpoint in the callback (see tb.Callback.Data)
+	Returns the new node
+*/
+func (e *Node) AddSubData(text, data string, endpoint Callback) *Node {
+	return e.AddSub(text, endpoint).SetData(data)
+}
+
+/*
+	Attaches data to the node's button, so an endpoint shared by many nodes
+	(e.g. of provided items) can tell which one was pressed by the callback data
+	Telegram limits the data along with the button's unique to 64 bytes, longer data is replaced with a hash of it
+	Returns the current node
+*/
+func (e *Node) SetData(data string) *Node {
+	e.data = data
+	return e
+}
+
+/*
+	Get data attached to the node's button
+*/
+func (e *Node) GetData() string {
+	return e.data
+}
+
+/*
+	Adds a new node that opens a link instead of making a callback
+	Returns the current node
+*/
+func (e *Node) AddURL(text, url string) *Node {
+	e.AddSub(text, nil).url = url
+	return e
+}
+
+/*
+	Adds a new node that switches a user to an inline query of the bot in a chosen chat
+	instead of making a callback
+	Returns the current node
+*/
+func (e *Node) AddSwitchInline(text, query string) *Node {
+	e.AddSub(text, nil).query = query
+	return e
+}
+
 /*
 	Adds many new sub nodes
 	Returns the current node
@@ -162,19 +269,155 @@ func (e *Node) AddManySub(elements []*Node) *Node {
 	return e
 }
 
+/*
+	Sets a provider that generates children of the node every time its page is displayed
+	Static children of the node are ignored while the provider is set
+	Returns the current node
+*/
+func (e *Node) SetProvider(provider NodeProvider) *Node {
+	e.provider = provider
+	return e
+}
+
+/*
+	Sets a maximum number of children displayed on a page at once
+	Children that do not fit are split into pages with navigation buttons
+	Zero or a negative size disables pagination
+	Returns the current node
+*/
+func (e *Node) SetPageSize(n int) *Node {
+	e.pageSize = n
+	if n > 0 {
+		e.control("prev", func(to tb.Recipient, d *Dialog) error { return e.turnPage(to, d, -1) })
+		e.control("next", func(to tb.Recipient, d *Dialog) error { return e.turnPage(to, d, 1) })
+	}
+	return e
+}
+
+/*
+	Sets a number of buttons displayed in a row on the node's page
+	Must be called before the flow is built
+	Returns the current node
+*/
+func (e *Node) SetColumns(n int) *Node {
+	e.SetLayout(Columns(n))
+	e.columns = n
+	return e
+}
+
+/*
+	Sets a layout that arranges buttons on the node's page
+	Must be called before the flow is built
+	Returns the current node
+*/
+func (e *Node) SetLayout(layout LayoutFunc) *Node {
+	e.layout, e.columns = layout, 0
+	return e
+}
+
+/*
+	Arranges buttons in rows according to the node's layout
+*/
+func (e *Node) arrange(buttons []tb.InlineButton) [][]tb.InlineButton {
+	if e.layout == nil {
+		return SingleColumn(buttons)
+	}
+	return e.layout(buttons)
+}
+
+/*
+	Sets a predicate that decides whether the node's button is displayed in a dialog
+	The predicate is evaluated every time the parent page is displayed
+	Returns the current node
+*/
+func (e *Node) SetVisible(visible func(e *Node, d *Dialog) bool) *Node {
+	e.visible = visible
+	return e
+}
+
+/*
+	Sets a function that generates a label of the node's button (e.g. "Notifications: ON")
+	The function is evaluated every time the parent page is displayed and replaces the localized label
+	Returns the current node
+*/
+func (e *Node) SetLabelFunc(label func(d *Dialog, lang string) string) *Node {
+	e.labelFunc = label
+	return e
+}
+
+/*
+	Sets a label that is displayed in every locale instead of the localized one (e.g. a label typed by an admin)
+	Must be called before the flow is built
+	Returns the current node
+*/
+func (e *Node) SetLabel(text string) *Node {
+	e.title = text
+	return e
+}
+
+/*
+	Hides the node's button from every dialog, unlike SetVisible the node stays hidden
+	when the tree is saved as a definition (see Menu.Definition)
+	Returns the current node
+*/
+func (e *Node) SetHidden(hidden bool) *Node {
+	e.hidden = hidden
+	return e
+}
+
+/*
+	Checks if the node's button is hidden from every dialog
+*/
+func (e *Node) IsHidden() bool {
+	return e.hidden
+}
+
+/*
+	Sets a function that gives arguments for the translation of the node's label
+	The translation is used as a format (e.g. "Cart (%d)") and is filled every time the parent page is displayed
+	Returns the current node
+*/
+func (e *Node) SetTrArgs(args func(d *Dialog) []interface{}) *Node {
+	e.trArgs = args
+	return e
+}
+
+/*
+	Enables or disables the node's button for the user
+	A disabled button is still displayed, but pressing it only shows an alert
+	The change is displayed the next time the menu is updated
+	Returns the current node
+*/
+func (e *Node) SetEnabled(c *tb.Callback, enabled bool) *Node {
+	if d, ok := e.flow.dialog(c); ok {
+		d.setEnabled(e, enabled)
+	}
+	return e
+}
+
+/*
+	Sets a locale key of the caption that is displayed automatically
+	every time a user enters the node's page
+	The caption is translated to the language of the dialog, SetCaption overrides it until the page is left
+	Returns the current node
+*/
+func (e *Node) SetCaptionKey(key string) *Node {
+	e.captionKey = key
+	return e
+}
+
 /*
 	Sets a new caption for the flow
 	that will be updated in the next menu iteration
 	params are automatically placed in the text if provided
 */
 func (e *Node) SetCaption(c *tb.Callback, text string, params ...interface{}) *Node {
-	if d, ok := e.flow.GetDialog(c.Sender.Recipient()); ok {
+	if d, ok := e.flow.dialog(c); ok {
 		if len(params) > 0 {
 			text = fmt.Sprintf(text, params...)
 		}
-		if d.Message.Text != text {
-			d.Message.Text = text
-			e.mustUpdate = true
+		if d.setText(text) {
+			d.markPending(e)
 		}
 	}
 	return e
@@ -184,86 +427,420 @@ func (e *Node) SetCaption(c *tb.Callback, text string, params ...interface{}) *N
 	Gets a language currently used in a dialog by the user
 */
 func (e *Node) GetLanguage(c *tb.Callback) string {
-	if d, ok := e.flow.GetDialog(c.Sender.Recipient()); ok {
+	if d, ok := e.flow.dialog(c); ok {
 		return d.Language
 	}
-	return e.flow.defaultLocale
+	return e.flow.languageOf(c.Sender)
 }
 
 /*
 	Sets a language for the user's dialog
 */
 func (e *Node) SetLanguage(c *tb.Callback, lang string) *Node {
-	if d, ok := e.flow.GetDialog(c.Sender.Recipient()); ok {
+	if d, ok := e.flow.dialog(c); ok {
 		d.Language = lang
-		e.mustUpdate = true
-		e.next(c)
+		d.markPending(e)
+		if err := e.next(c.Sender, d); err != nil {
+			e.flow.fail(err, c, e)
+		}
 	}
 	return e
 }
 
 /*
-	Updates the menu
+	Updates the menu and displays the page of a specified node
 */
-func (e *Node) update(recipient tb.Recipient, d *Dialog, markup *tb.ReplyMarkup) {
-	newMsg, err := e.flow.bot.Edit(d.Message, d.Message.Text, markup)
+func (e *Node) update(recipient tb.Recipient, d *Dialog, page *Node) error {
+	prevPosition, prevText, prevKey := d.Position, d.text, d.textKey
+	d.Position = page
+	if page != prevPosition && page.captionKey != "" {
+		d.textKey = page.captionKey
+	}
+	if text, ok := d.pageCaption(page); ok && page != prevPosition {
+		d.text, d.textKey = text, ""
+	}
+	if !e.flow.throttle(d) {
+		// the deferred edit displays the page
+		d.record(prevPosition, page)
+		return nil
+	}
+	newMsg, err := e.flow.edit(d, page.markup(d))
 	if err != nil {
-		log.Println("failed to continue", recipient.Recipient(), err)
-		return
+		d.Position, d.text, d.textKey = prevPosition, prevText, prevKey
+		return err
 	}
-	e.mustUpdate = false
-	d.Message = newMsg
-	d.Position = e
+	d.displayed()
+	d.record(prevPosition, page)
+	e.flow.setMessage(d, newMsg)
+	e.flow.navigated(prevPosition, page, d)
+	return nil
 }
 
 /*
 	Goes back to the previous menu
 */
-func (e *Node) back(c *tb.Callback) *Node {
-	d, ok := e.flow.GetDialog(c.Sender.Recipient())
-	if !ok {
-		log.Println(c.Sender.ID, "does not exist")
-		return nil
+func (e *Node) back(to tb.Recipient, d *Dialog) error {
+	if prev := d.previous(); prev != nil {
+		return e.update(to, d, prev)
 	}
 	if e.prev == nil || e.prev.prev == nil {
-		if e.mustUpdate {
-			e.update(c.Sender, d, e.flow.root.markups[d.Language])
-			return e
+		if d.mustUpdate(e) {
+			return e.update(to, d, e.flow.root)
 		}
 		return nil
 	}
-	newMsg, err := e.flow.bot.Edit(d.Message, d.Message.Text, e.prev.prev.markups[d.Language])
-	if err != nil {
-		log.Println("failed to back", c.Sender.ID, err)
+	return e.update(to, d, e.prev.prev)
+}
+
+/*
+	Continues to the following and/or updates the menu
+*/
+func (e *Node) next(to tb.Recipient, d *Dialog) error {
+	hasPage := e.HasPage()
+	if !hasPage && !d.mustUpdate(e) {
 		return nil
 	}
-	d.Message = newMsg
-	d.Position = e.prev
-	return e.prev
+	page := e
+	if !hasPage {
+		page = e.prev
+	}
+	return e.update(to, d, page)
 }
 
 /*
-	Continues to the following and/or updates the menu
+	Gets a markup of the node's page for a dialog along with the progress bar of the dialog
+*/
+func (e *Node) markup(d *Dialog) *tb.ReplyMarkup {
+	return e.progressRow(d, e.pageMarkup(d))
+}
+
+/*
+	Gets a markup of the node's page for a dialog
+	Markups of provided or paginated pages are generated on every call, the other ones are copies of built markups
+*/
+func (e *Node) pageMarkup(d *Dialog) *tb.ReplyMarkup {
+	lang := e.flow.resolve(d.Language)
+	if !e.dynamic(d) {
+		variant := d.GetVariant()
+		e.flow.tree.RLock()
+		defer e.flow.tree.RUnlock()
+		if variant != "" {
+			if markup, ok := e.materializedVariant(variant, lang); ok {
+				return copyMarkup(markup)
+			}
+		}
+		_, markup := e.materialized(lang)
+		return copyMarkup(markup)
+	}
+	_, buttons := e.display(d)
+	e.flow.tree.RLock()
+	defer e.flow.tree.RUnlock()
+	return &tb.ReplyMarkup{
+		InlineKeyboard: e.decorate(lang, e.paginate(d, buttons)),
+	}
+}
+
+/*
+	Gets children of the node's page that are displayed in a dialog along with their decorated buttons
+*/
+func (e *Node) display(d *Dialog) ([]*Node, []tb.InlineButton) {
+	var nodes []*Node
+	var buttons []tb.InlineButton
+	lang := e.flow.resolve(d.Language)
+	if e.provider != nil {
+		nodes, buttons = e.provide(d)
+	} else {
+		e.flow.tree.RLock()
+		nodes = e.nodes
+		buttons, _ = e.materialized(lang)
+		e.flow.tree.RUnlock()
+	}
+	filter := e.flow.variant(d)
+	displayedNodes := make([]*Node, 0, len(nodes))
+	displayed := make([]tb.InlineButton, 0, len(buttons))
+	for i, btn := range buttons {
+		if !nodes[i].isVisible(d) || !nodes[i].isAuthorized(d) || filter != nil && !filter(nodes[i]) {
+			continue
+		}
+		if nodes[i].labelFunc != nil {
+			btn.Text = nodes[i].labelFunc(d, lang)
+		} else if nodes[i].trArgs != nil {
+			btn.Text = fmt.Sprintf(btn.Text, nodes[i].trArgs(d)...)
+		}
+		if nodes[i].marker != nil {
+			btn.Text = nodes[i].marker(d) + btn.Text
+		}
+		if !d.IsEnabled(nodes[i]) {
+			btn.Text = e.flow.disabledPrefix + btn.Text
+		}
+		displayedNodes = append(displayedNodes, nodes[i])
+		displayed = append(displayed, btn)
+	}
+	displayedNodes, displayed = e.ordered(d, displayedNodes, displayed)
+	e.flow.numberButtons(displayed)
+	return displayedNodes, displayed
+}
+
+/*
+	Checks if the node's page depends on a dialog and has to be generated on every display
 */
-func (e *Node) next(c *tb.Callback) {
-	nodes := len(e.nodes)
-	if nodes < 1 && !e.mustUpdate {
+func (e *Node) dynamic(d *Dialog) bool {
+	if e.provider != nil || e.pageSize > 0 || e.flow.authorize != nil || e.order != nil || e.flow.numbered {
+		return true
+	}
+	for _, child := range e.nodes {
+		if child.personal() || !d.IsEnabled(child) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Checks if the node's button depends on a dialog
+*/
+func (e *Node) personal() bool {
+	return e.hidden || e.visible != nil || e.marker != nil || e.labelFunc != nil || e.trArgs != nil || e.experiment != nil
+}
+
+/*
+	Checks if the node's button is displayed in a dialog
+*/
+func (e *Node) isVisible(d *Dialog) bool {
+	return !e.hidden && (e.visible == nil || e.visible(e, d)) && e.inVariant(d)
+}
+
+/*
+	Checks if the node's button can be reached by taps in the dialog: the node and all of its ancestors are visible
+*/
+func (e *Node) isReachable(d *Dialog) bool {
+	for node := e; node != nil && node.prev != nil; node = node.parent(d) {
+		if !node.isVisible(d) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+	Generates buttons for the children given by the provider
+*/
+func (e *Node) provide(d *Dialog) ([]*Node, []tb.InlineButton) {
+	nodes := e.provider.Nodes(e, d)
+	lang := e.flow.resolve(d.Language)
+	buttons := make([]tb.InlineButton, len(nodes))
+	base := fnvAdd(fnvOffset, e.relativePath()+"/")
+	seen := make(map[string]int, len(nodes))
+	e.flow.tree.Lock()
+	defer e.flow.tree.Unlock()
+	for i, child := range nodes {
+		child.flow = e.flow
+		child.prev = e
+		child.raw = true
+		// provided nodes are created anew, so their ids follow the page they are provided for
+		child.id = sumId(fnvAdd(base, child.text), seen[child.text])
+		seen[child.text]++
+		child.followId()
+		if len(child.nodes) > 0 && child.markups[lang] == nil {
+			child.build(e.path, lang)
+		} else {
+			child.path = e.path + "/" + child.text
+		}
+		buttons[i] = child.button(lang, child.label(lang))
+	}
+	return nodes, buttons
+}
+
+/*
+	Gives the descendants of a provided node ids that follow the id of the node,
+	so the routes of descendants provided anew on every display replace the previous ones instead of piling up
+	Mounted subtrees keep their ids, since they are shared with other pages
+*/
+func (e *Node) followId() {
+	base := fnvAdd(fnvOffset, e.id+"/")
+	seen := make(map[string]int, len(e.nodes))
+	for _, child := range e.nodes {
+		if child.mounted {
+			continue
+		}
+		child.id = sumId(fnvAdd(base, child.text), seen[child.text])
+		seen[child.text]++
+		child.followId()
+	}
+}
+
+/*
+	Arranges buttons in rows and cuts out the page that the dialog is currently on
+*/
+func (e *Node) paginate(d *Dialog, buttons []tb.InlineButton) [][]tb.InlineButton {
+	if e.pageSize < 1 || len(buttons) <= e.pageSize {
+		return e.arrange(buttons)
+	}
+	pages := (len(buttons) + e.pageSize - 1) / e.pageSize
+	page := d.Page(e)
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	d.setPage(e, page)
+	from := page * e.pageSize
+	to := from + e.pageSize
+	if to > len(buttons) {
+		to = len(buttons)
+	}
+	rows := e.arrange(buttons[from:to:to])
+	nav := make([]tb.InlineButton, 0, 2)
+	if page > 0 {
+		nav = append(nav, e.controlButton("prev", e.flow.prevLabel))
+	}
+	if page < pages-1 {
+		nav = append(nav, e.controlButton("next", e.flow.nextLabel))
+	}
+	return append(rows, nav)
+}
+
+/*
+	Appends rows of auxiliary buttons that are added to every page automatically
+	The rows are mirrored for right-to-left locales
+*/
+func (e *Node) decorate(lang string, rows [][]tb.InlineButton) [][]tb.InlineButton {
+	var row []tb.InlineButton
+	if _, ok := e.controls["back"]; ok {
+		row = append(row, e.controlButton("back", e.flow.engine.Lang(lang).Tr(e.flow.id+"/"+e.flow.backKey)))
+	}
+	if _, ok := e.controls["home"]; ok {
+		row = append(row, e.controlButton("home", e.flow.engine.Lang(lang).Tr(e.flow.id+"/"+e.flow.homeKey)))
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return e.flow.direct(lang, rows)
+}
+
+/*
+	Switches the page of the node's children by a delta
+*/
+func (e *Node) turnPage(to tb.Recipient, d *Dialog, delta int) error {
+	d.setPage(e, d.Page(e)+delta)
+	return e.update(to, d, e)
+}
+
+/*
+	Takes the dialog from the node's page to the parent page
+*/
+func (e *Node) leave(to tb.Recipient, d *Dialog) error {
+	d.cancelInput()
+	if prev := d.previous(); prev != nil {
+		return e.update(to, d, prev)
+	}
+	return e.update(to, d, e.parent(d))
+}
+
+/*
+	Registers a handler for an auxiliary button of the node (e.g. page navigation)
+	The callback is accepted before the handler is called
+	Only one handler per action is registered
+*/
+func (e *Node) control(action string, handler func(to tb.Recipient, d *Dialog) error) {
+	if _, ok := e.controls[action]; ok {
 		return
 	}
-	d, ok := e.flow.GetDialog(c.Sender.Recipient())
-	if !ok {
-		log.Println(c.Sender.ID, "does not exist")
+	btn := tb.InlineButton{
+		Unique: e.flow.id,
+		Data:   e.controlRoute(action),
+	}
+	e.flow.route(btn.Data, func(c *tb.Callback) {
+		d, ok := e.admit(c, btn.Data, false)
+		if !ok {
+			return
+		}
+		audited := e.flow.auditTap(e, c, d)
+		err := handler(c.Sender, d)
+		if err != nil {
+			e.flow.fail(err, c, e)
+		}
+		audited(d, controlResult(action), err)
+	})
+	e.controls[action] = btn
+	e.actions[action] = handler
+}
+
+/*
+	Gets an auxiliary button of the node with a specified text
+*/
+func (e *Node) controlButton(action, text string) tb.InlineButton {
+	btn := e.controls[action]
+	btn.Text = text
+	return btn
+}
+
+/*
+	Gets a localized label of the node's button
+	Nodes with a shared label are localized relatively to the flow instead of their own path
+	Provided nodes are not a part of the locale, so their text is displayed as is
+*/
+func (e *Node) label(lang string) string {
+	if e.raw {
+		return e.text
+	}
+	if e.title != "" {
+		return e.title
+	}
+	if e.labelKey != "" {
+		return e.flow.engine.Lang(lang).Tr(e.flow.id + "/" + e.labelKey)
+	}
+	return e.flow.engine.Lang(lang).Tr(e.path)
+}
+
+/*
+	Creates an inline button for the node and registers its handler
+*/
+func (e *Node) button(lang, text string) tb.InlineButton {
+	if e.url != "" {
+		return tb.InlineButton{Text: text, URL: e.url}
+	}
+	if e.query != "" {
+		return tb.InlineButton{Text: text, InlineQuery: e.query}
+	}
+	btn := tb.InlineButton{
+		Unique: e.flow.id,
+		Text:   text,
+		Data:   e.compactData(),
+	}
+	e.route()
+	return btn
+}
+
+/*
+	Registers a handler of the node's button without creating the button
+*/
+func (e *Node) register() {
+	if e.url != "" || e.query != "" {
 		return
 	}
-	markup := e.markups
-	if nodes < 1 {
-		markup = e.prev.markups
+	// compacted data is expanded even before the button is displayed
+	e.compactData()
+	e.route()
+}
+
+/*
+	Registers a handler of the node's button in the flow
+*/
+func (e *Node) route() {
+	if e.input != nil {
+		e.flow.route(e.id, e.flow.expand(e.handleInput))
+	} else if e.endpoint != nil {
+		e.flow.route(e.id, e.flow.expand(e.handle))
+	} else {
+		e.flow.route(e.id, e.flow.expand(e.handleDeadEnd))
 	}
-	e.update(c.Sender, d, markup[d.Language])
 }
 
 /*
 	Builds the flow and creates markups for a tree of nodes in a specified locale
+	With a markup cache only the handlers are registered and markups are created on display
 */
 func (e *Node) build(basePath, lang string) {
 	if e.prev != nil {
@@ -271,51 +848,194 @@ func (e *Node) build(basePath, lang string) {
 	} else {
 		e.path = basePath
 	}
-	buttons := make([][]tb.InlineButton, len(e.nodes))
-	for i, child := range e.nodes {
-		child.build(e.path, lang)
-		buttons[i] = []tb.InlineButton{
-			{
-				Unique: strconv.FormatInt(time.Now().Unix(), 10) + uniquePrefix + lang + child.id,
-				Text:   e.flow.engine.Lang(lang).Tr(child.path),
-			},
-		}
-		if child.endpoint != nil {
-			e.flow.bot.Handle(&buttons[i][0], child.handle)
-		} else {
-			e.flow.bot.Handle(&buttons[i][0], child.handleDeadEnd)
-		}
+	if e.prev != nil && e.flow.backKey != "" {
+		e.control("back", e.leave)
+	}
+	if e.prev != nil && e.prev.prev != nil && e.flow.homeKey != "" {
+		e.control("home", e.home)
 	}
-	e.markups[lang] = &tb.ReplyMarkup{
-		InlineKeyboard: buttons,
+	for _, child := range e.nodes {
+		child.build(e.childPath(child, e.path), lang)
 	}
+	if e.flow.markupCache != nil {
+		for _, child := range e.nodes {
+			child.register()
+		}
+		return
+	}
+	e.buttons[lang], e.markups[lang] = e.materialize(lang)
 }
 
 /*
 	Default handler for pagination
 */
 func (e *Node) handle(c *tb.Callback) {
-	err := e.flow.bot.Respond(c)
-	if err != nil {
-		log.Println("failed to respond", c.Sender.ID, err)
+	d, ok := e.acceptDeferred(c)
+	if !ok {
 		return
 	}
-	result := e.endpoint(e, c)
+	e.call(c, d)
+}
+
+/*
+	Calls the node's endpoint and navigates the dialog according to the result
+*/
+func (e *Node) call(c *tb.Callback, d *Dialog) {
+	if !e.flow.gate.enter() {
+		return
+	}
+	defer e.flow.gate.leave()
+	e.flow.expectRespond(c)
+	audited := e.flow.auditTap(e, c, d)
+	var refusal AuditResult
+	switch {
+	case e.guarded(c, d):
+		refusal = AuditDenied
+	case e.overQuota(c, d):
+		refusal = AuditOverQuota
+	case e.cooling(c, d):
+		refusal = AuditCooling
+	}
+	if refusal != "" {
+		e.flow.settle(c, 0)
+		audited(d, refusal, nil)
+		return
+	}
+	d.cancelInput()
+	var err error
+	keep := e.flow.remember(d)
+	result := e.flow.wrap(e.endpoint)(e, c)
+	keep()
+	e.flow.settle(c, e.respondTimeout())
+	if result != Stay {
+		// the endpoint might have started a new menu
+		current, ok := e.flow.dialog(c)
+		if !ok {
+			e.flow.fail(ErrDialogNotFound, c, e)
+			audited(d, auditResult(result), ErrDialogNotFound)
+			return
+		}
+		d = current
+	}
 	if result == Forward {
-		e.next(c)
+		err = e.next(c.Sender, d)
 	} else if result == Back {
-		e.back(c)
+		err = e.back(c.Sender, d)
+	}
+	if err != nil {
+		e.flow.fail(err, c, e)
 	}
+	audited(d, auditResult(result), err)
 }
 
 /*
 	Handler for menu buttons with no provided endpoint (callback)
 */
 func (e *Node) handleDeadEnd(c *tb.Callback) {
-	err := e.flow.bot.Respond(c)
-	if err != nil {
-		log.Println("failed to respond", c.Sender.ID, err)
+	d, ok := e.accept(c)
+	if !ok {
 		return
 	}
-	e.next(c)
+	audited := e.flow.auditTap(e, c, d)
+	d.cancelInput()
+	err := e.next(c.Sender, d)
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	audited(d, AuditForward, err)
+}
+
+/*
+	Responds to a callback and retrieves the dialog of the sender
+	Callbacks without a dialog (e.g. sent from a menu displayed before a restart) are treated as stale
+*/
+func (e *Node) accept(c *tb.Callback) (*Dialog, bool) {
+	d, ok := e.admit(c, e.id, false)
+	if ok {
+		d.use(e)
+	}
+	return d, ok
+}
+
+/*
+	Retrieves the dialog of the sender, leaving the callback to be responded after the endpoint
+*/
+func (e *Node) acceptDeferred(c *tb.Callback) (*Dialog, bool) {
+	d, ok := e.admit(c, e.id, true)
+	if ok {
+		d.use(e)
+	}
+	return d, ok
+}
+
+/*
+	Answers a callback with an alert
+	Presses that are not callbacks (e.g. of a reply keyboard) can not be answered, so they get the text as a message
+*/
+func (f *Menu) alert(c *tb.Callback, text string) error {
+	if c.ID == "" {
+		f.pace(c.Sender)
+		_, err := f.bot.Send(c.Sender, text)
+		return err
+	}
+	return f.bot.Respond(c, &tb.CallbackResponse{Text: text, ShowAlert: true})
+}
+
+/*
+	Checks a callback and retrieves the dialog of the sender
+	The callback is responded right away unless it is deferred
+	Repeated taps on the button are ignored (see Menu.WithDebounce)
+*/
+func (e *Node) admit(c *tb.Callback, button string, deferred bool) (*Dialog, bool) {
+	if e.flow.gate.closed() {
+		if err := e.flow.respond(c); err != nil {
+			e.flow.fail(err, c, e)
+		}
+		return nil, false
+	}
+	d, ok := e.flow.dialog(c)
+	if e.flow.foreign(c, d, ok) || ok && e.flow.locked(c, d) {
+		return nil, false
+	}
+	if ok && e.denied(c, d) {
+		e.flow.auditRefused(e, c, d, AuditDenied)
+		return nil, false
+	}
+	if ok && d.repeated(c, button, e.flow.debounce) {
+		if err := e.flow.respond(c); err != nil {
+			e.flow.fail(err, c, e)
+		}
+		return nil, false
+	}
+	if ok && !d.IsEnabled(e) {
+		// disabled buttons only answer with an alert
+		if err := e.flow.alert(c, e.flow.disabledAlert); err != nil {
+			e.flow.fail(err, c, e)
+		}
+		e.flow.auditRefused(e, c, d, AuditDisabled)
+		return nil, false
+	}
+	if c.ID != "" && (!ok || !deferred) {
+		if err := e.flow.bot.Respond(c); err != nil {
+			e.flow.fail(err, c, e)
+			return nil, false
+		}
+	}
+	if !ok {
+		e.flow.fail(ErrDialogNotFound, c, e)
+		e.flow.stale(c)
+		return nil, false
+	}
+	e.flow.reportPressed(e)
+	if e.flow.perMessage {
+		// the dialog becomes the one the user is working with
+		var chat *tb.Chat
+		if c.Message != nil {
+			chat = c.Message.Chat
+		}
+		e.flow.setDialog(e.flow.userKey(chat, c.Sender), d)
+	} else {
+		d.touch()
+	}
+	return d, true
 }
@@ -0,0 +1,35 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"time"
+)
+
+/*
+	Sends a short notice (e.g. "Saved!") below the menu that a callback was made from
+	and deletes it after a specified time, so the caption of the menu stays as it is
+	The text is localized the same way as for Alert, zero ttl keeps the notice
+*/
+func (e *Node) Notify(c *tb.Callback, text string, ttl time.Duration) error {
+	lang := e.flow.languageOf(c.Sender)
+	if d, ok := e.flow.dialog(c); ok {
+		lang = d.Language
+	}
+	var to tb.Recipient = c.Sender
+	if c.Message != nil && c.Message.Chat != nil {
+		to = c.Message.Chat
+	}
+	e.flow.pace(to)
+	msg, err := e.flow.bot.Send(to, e.flow.translate(lang, text, text), tb.Silent)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		time.AfterFunc(ttl, func() {
+			if err := e.flow.bot.Delete(msg); err != nil {
+				e.flow.fail(err, nil, e)
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sort"
+	"time"
+)
+
+/*
+	Sets a function that orders the node's children on its page instead of the order they were added in
+	The order is applied when the markup of the page is built
+	Returns the current node
+*/
+func (e *Node) SetSort(less func(a, b *Node) bool) *Node {
+	e.less = less
+	return e
+}
+
+/*
+	Sets a function that orders the node's children for every dialog (e.g. RecentlyUsed)
+	Children that the function does not order keep the order of SetSort, the page is generated on every display
+	Returns the current node
+*/
+func (e *Node) SetOrder(order func(d *Dialog, a, b *Node) bool) *Node {
+	e.order = order
+	return e
+}
+
+/*
+	An order of children that puts the ones pressed most recently in the dialog first
+*/
+func RecentlyUsed(d *Dialog, a, b *Node) bool {
+	return d.lastUsed(a) > d.lastUsed(b)
+}
+
+/*
+	Remembers that the node's button was pressed in the dialog
+	Only internal use is intended
+*/
+func (d *Dialog) use(e *Node) {
+	d.mx.Lock()
+	if d.used == nil {
+		d.used = make(map[string]int64)
+	}
+	d.used[e.id] = time.Now().UnixNano()
+	d.mx.Unlock()
+}
+
+/*
+	Gets the time the node's button was last pressed in the dialog, zero if it was never pressed
+*/
+func (d *Dialog) lastUsed(e *Node) int64 {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.used[e.id]
+}
+
+/*
+	Orders children of the node along with their buttons, the dialog is nil for markups shared by all dialogs
+*/
+func (e *Node) ordered(d *Dialog, nodes []*Node, buttons []tb.InlineButton) ([]*Node, []tb.InlineButton) {
+	if e.less == nil && (e.order == nil || d == nil) {
+		return nodes, buttons
+	}
+	index := make([]int, len(nodes))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		a, b := nodes[index[i]], nodes[index[j]]
+		if e.order != nil && d != nil {
+			if e.order(d, a, b) {
+				return true
+			}
+			if e.order(d, b, a) {
+				return false
+			}
+		}
+		return e.less != nil && e.less(a, b)
+	})
+	orderedNodes := make([]*Node, len(nodes))
+	orderedButtons := make([]tb.InlineButton, len(buttons))
+	for i, j := range index {
+		orderedNodes[i], orderedButtons[i] = nodes[j], buttons[j]
+	}
+	return orderedNodes, orderedButtons
+}
@@ -0,0 +1,67 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const ownerAlertKey = "not_yours"
+
+var ErrNoOwner = errors.New("the menu has no owner to be locked to")
+
+/*
+	Allows only the user that a menu was sent for to press its buttons
+	Other users get an alert localized by "<flow id>/not_yours"
+	It is useful when menus are shared by a group chat (see WithDialogPerMessage)
+	A menu of a group chat has no owner unless it is started with StartFor,
+	so Start and StartAt return ErrNoOwner for group chats instead of sending a menu that anyone could press
+*/
+func (f *Menu) WithOwnerLock() *Menu {
+	f.ownerLock = true
+	return f
+}
+
+/*
+	Gets an identificator of the user that the menu was sent for
+	It is empty for menus that were sent to a group chat without a user
+*/
+func (d *Dialog) GetOwner() string {
+	return d.owner
+}
+
+/*
+	Rejects a press made by a user that does not own the dialog
+	Returns false if the press is allowed
+*/
+func (f *Menu) locked(c *tb.Callback, d *Dialog) bool {
+	if !f.ownerLock || d.owner == "" || d.owner == c.Sender.Recipient() {
+		return false
+	}
+	if err := f.alert(c, f.notYours(d.Language)); err != nil {
+		f.fail(err, c, nil)
+	}
+	return true
+}
+
+/*
+	Gets a localized alert for users pressing a menu of another user
+*/
+func (f *Menu) notYours(lang string) string {
+	return f.translate(lang, ownerAlertKey, f.foreignAlert)
+}
+
+/*
+	Gets an owner of a menu sent to a recipient
+	Only users and private chats have an owner
+*/
+func ownerOf(to tb.Recipient) string {
+	switch r := to.(type) {
+	case *tb.User:
+		return r.Recipient()
+	case *tb.Chat:
+		if r.Type == tb.ChatPrivate {
+			return r.Recipient()
+		}
+	}
+	return ""
+}
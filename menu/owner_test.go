@@ -0,0 +1,23 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestOwnerLockNeedsOwner(t *testing.T) {
+	flow, bot := newFlow(t, "owner")
+	flow.WithOwnerLock().Build("en")
+	group := &tb.Chat{ID: -1, Type: tb.ChatGroup}
+	if err := flow.Start(group, "caption", "en"); err != menu.ErrNoOwner {
+		t.Fatalf("a locked menu is started in a group without an owner: %v", err)
+	}
+	if len(bot.Sends) != 0 {
+		t.Fatal("a menu without an owner is sent")
+	}
+	if err := flow.StartFor(group, &tb.User{ID: 1}, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+}
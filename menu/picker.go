@@ -0,0 +1,88 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Native names of common languages along with their flags
+*/
+var languageNames = map[string]string{
+	"ar": "🇸🇦 العربية",
+	"be": "🇧🇾 Беларуская",
+	"cs": "🇨🇿 Čeština",
+	"de": "🇩🇪 Deutsch",
+	"en": "🇬🇧 English",
+	"es": "🇪🇸 Español",
+	"fa": "🇮🇷 فارسی",
+	"fr": "🇫🇷 Français",
+	"he": "🇮🇱 עברית",
+	"hi": "🇮🇳 हिन्दी",
+	"id": "🇮🇩 Bahasa Indonesia",
+	"it": "🇮🇹 Italiano",
+	"ja": "🇯🇵 日本語",
+	"kk": "🇰🇿 Қазақ тілі",
+	"ko": "🇰🇷 한국어",
+	"nl": "🇳🇱 Nederlands",
+	"pl": "🇵🇱 Polski",
+	"pt": "🇵🇹 Português",
+	"ro": "🇷🇴 Română",
+	"ru": "🇷🇺 Русский",
+	"sv": "🇸🇪 Svenska",
+	"tr": "🇹🇷 Türkçe",
+	"uk": "🇺🇦 Українська",
+	"uz": "🇺🇿 Oʻzbekcha",
+	"zh": "🇨🇳 中文",
+}
+
+/*
+	Creates a provider that lists every built locale of the flow by its native name and flag
+	(e.g. parent.AddSub("language", nil).SetProvider(menu.NewLanguagePicker(nil)))
+	Pressing a locale switches the language of the dialog and rebuilds the picker's page in the new language,
+	the callback is called after the language is switched, nil callbacks are allowed
+	A result of the callback other than Stay is treated as a result of a node's callback
+*/
+func NewLanguagePicker(onSelect Callback) NodeProvider {
+	return NodeProviderFunc(func(e *Node, d *Dialog) []*Node {
+		locales := e.flow.GetLocales()
+		nodes := make([]*Node, len(locales))
+		for i, lang := range locales {
+			nodes[i] = e.flow.NewNode(languageName(lang), pickLanguage(lang, onSelect))
+		}
+		return nodes
+	})
+}
+
+/*
+	Creates an endpoint that switches the language of a dialog
+*/
+func pickLanguage(lang string, onSelect Callback) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		d, ok := e.flow.dialog(c)
+		if !ok {
+			return Stay
+		}
+		d.Language = lang
+		result := Stay
+		if onSelect != nil {
+			result = onSelect(e, c)
+		}
+		if result != Stay {
+			return result
+		}
+		d.markPending(e)
+		return Forward
+	}
+}
+
+/*
+	Gets a native name of a language with its flag, unknown languages are named by their tag
+*/
+func languageName(lang string) string {
+	for candidate := lang; candidate != ""; candidate = baseLocale(candidate) {
+		if name, ok := languageNames[candidate]; ok {
+			return name
+		}
+	}
+	return lang
+}
@@ -0,0 +1,110 @@
+package menu
+
+import (
+	"fmt"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+const progressWidth = 10
+
+/*
+	Renders a progress bar of a specified width for a value between 0 and 1 (e.g. "▰▰▰▱▱ 60%")
+*/
+func ProgressBar(value float64, width int) string {
+	if value < 0 {
+		value = 0
+	} else if value > 1 {
+		value = 1
+	}
+	filled := int(value*float64(width) + 0.5)
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", width-filled) + fmt.Sprintf(" %d%%", int(value*100+0.5))
+}
+
+/*
+	Makes progress bars be displayed as a button row at the bottom of the menu instead of the caption
+	Pressing the button does nothing
+*/
+func (f *Menu) WithProgressButton() *Menu {
+	f.progressButton = true
+	return f
+}
+
+/*
+	Displays a progress bar of a long-running operation in the menu that a callback was made from
+	and updates the menu right away, the value is clamped between 0 and 1
+	The bar stays until ClearProgress is called, an endpoint that calls it should return Stay
+*/
+func (e *Node) SetProgress(c *tb.Callback, value float64) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	d.mx.Lock()
+	d.progress = ProgressBar(value, progressWidth)
+	d.mx.Unlock()
+	return e.redraw(c.Sender, d)
+}
+
+/*
+	Removes the progress bar from the menu that a callback was made from
+*/
+func (e *Node) ClearProgress(c *tb.Callback) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	d.mx.Lock()
+	d.progress = ""
+	d.mx.Unlock()
+	return e.redraw(c.Sender, d)
+}
+
+/*
+	Updates the page the dialog is on
+*/
+func (e *Node) redraw(to tb.Recipient, d *Dialog) error {
+	page := d.Position
+	if page == nil {
+		page = e.flow.root
+	}
+	return page.update(to, d, page)
+}
+
+/*
+	Gets the progress bar that the dialog displays
+*/
+func (d *Dialog) getProgress() string {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.progress
+}
+
+/*
+	Adds the progress bar of the dialog as a button row to a markup of the node's page
+*/
+func (e *Node) progressRow(d *Dialog, markup *tb.ReplyMarkup) *tb.ReplyMarkup {
+	bar := d.getProgress()
+	if !e.flow.progressButton || bar == "" || markup == nil {
+		return markup
+	}
+	e.flow.tree.Lock()
+	e.control("progress", func(to tb.Recipient, d *Dialog) error {
+		return nil
+	})
+	btn := e.controlButton("progress", bar)
+	e.flow.tree.Unlock()
+	rows := append(append([][]tb.InlineButton(nil), markup.InlineKeyboard...), []tb.InlineButton{btn})
+	return &tb.ReplyMarkup{InlineKeyboard: rows}
+}
+
+/*
+	Adds the progress bar of the dialog to a caption
+*/
+func (f *Menu) progressCaption(d *Dialog, text string) string {
+	bar := d.getProgress()
+	if f.progressButton || bar == "" {
+		return text
+	}
+	return text + "\n" + bar
+}
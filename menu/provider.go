@@ -0,0 +1,22 @@
+package menu
+
+/*
+	A provider generates children of a node at display time,
+	so that the page can be different for every user (e.g. a list of orders from a database)
+	Provided nodes are usually created with Menu.NewNode, their text is displayed as is
+*/
+type NodeProvider interface {
+	Nodes(e *Node, d *Dialog) []*Node
+}
+
+/*
+	An adapter that allows to use ordinary functions as node providers
+*/
+type NodeProviderFunc func(e *Node, d *Dialog) []*Node
+
+/*
+	Calls the provider function
+*/
+func (fn NodeProviderFunc) Nodes(e *Node, d *Dialog) []*Node {
+	return fn(e, d)
+}
@@ -0,0 +1,42 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"reflect"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestProvidedRoutesAreReused(t *testing.T) {
+	flow, bot := newFlow(t, "provider")
+	flow.GetRoot().AddSub("shop", nil).SetProvider(menu.NodeProviderFunc(func(e *menu.Node, d *menu.Dialog) []*menu.Node {
+		item := flow.NewNode("item", nil)
+		item.Add("buy", stay)
+		return []*menu.Node{item}
+	}))
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	var data [][]string
+	for i := 0; i < 2; i++ {
+		if err := flow.Start(user, "caption", "en"); err != nil {
+			t.Fatal(err)
+		}
+		if err := bot.Tap(flow, user, "shop"); err != nil {
+			t.Fatal(err)
+		}
+		if err := bot.Press(user, "item"); err != nil {
+			t.Fatal(err)
+		}
+		var page []string
+		for _, row := range bot.Last(user).ReplyMarkup.InlineKeyboard {
+			for _, btn := range row {
+				page = append(page, btn.Data)
+			}
+		}
+		data = append(data, page)
+	}
+	if !reflect.DeepEqual(data[0], data[1]) {
+		t.Fatalf("the provided page is routed by %v and then by %v", data[0], data[1])
+	}
+}
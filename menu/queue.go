@@ -0,0 +1,94 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sync"
+	"time"
+)
+
+/*
+	Paces all messages sent and edited by the flow, so mass updates (e.g. broadcasts or rebuilds)
+	stay under the limits of Telegram, a message waits for its turn instead of failing
+	The rates are numbers of messages per second for the whole bot and for each chat, zero disables a limit
+	A clone of the flow for the same bot shares the queue with the flow
+*/
+func (f *Menu) WithSendRate(global, perChat float64) *Menu {
+	f.queue.mx.Lock()
+	f.queue.global = rate(global)
+	f.queue.perChat = rate(perChat)
+	f.queue.mx.Unlock()
+	return f
+}
+
+/*
+	Waits for the turn of a message to a recipient
+	Only internal use is intended
+*/
+func (f *Menu) pace(to tb.Recipient) {
+	if to == nil {
+		return
+	}
+	f.queue.wait(to.Recipient())
+}
+
+/*
+	Gets an interval between messages for a number of messages per second
+*/
+func rate(perSecond float64) time.Duration {
+	if perSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / perSecond)
+}
+
+/*
+	A schedule of outgoing messages of the bot and of its chats
+*/
+type queue struct {
+	global  time.Duration
+	perChat time.Duration
+	next    time.Time
+	chats   map[string]time.Time
+	mx      sync.Mutex
+}
+
+/*
+	Creates a queue with no limits
+*/
+func newQueue() *queue {
+	return &queue{chats: make(map[string]time.Time)}
+}
+
+/*
+	Reserves the next free turn of a chat and waits until it comes
+	The turn comes after both the previous message of the bot and the previous message of the chat
+*/
+func (q *queue) wait(chat string) {
+	q.mx.Lock()
+	if q.global <= 0 && q.perChat <= 0 {
+		q.mx.Unlock()
+		return
+	}
+	now := time.Now()
+	at := now
+	if q.next.After(at) {
+		at = q.next
+	}
+	if next, ok := q.chats[chat]; ok && next.After(at) {
+		at = next
+	}
+	// the global turn is taken at the time the message is actually sent
+	q.next = at.Add(q.global)
+	if len(q.chats) >= idleBuckets {
+		for id, next := range q.chats {
+			if next.Before(now) {
+				delete(q.chats, id)
+			}
+		}
+	}
+	if q.perChat > 0 {
+		q.chats[chat] = at.Add(q.perChat)
+	}
+	q.mx.Unlock()
+	time.Sleep(at.Sub(now))
+}
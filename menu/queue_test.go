@@ -0,0 +1,35 @@
+package menu_test
+
+import (
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestSendRateOverChats(t *testing.T) {
+	flow, _ := newFlow(t, "queue")
+	flow.Build("en")
+	// a message every 100ms for the bot and every 200ms for a chat
+	flow.WithSendRate(10, 5)
+	started := time.Now()
+	first, second := &tb.User{ID: 1}, &tb.User{ID: 2}
+	if err := flow.Start(first, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(chan error)
+	go func() {
+		// waits for the turn of its chat at 200ms
+		waiting <- flow.Start(first, "caption", "en")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := flow.Start(second, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < 250*time.Millisecond {
+		t.Fatalf("the other chat is sent to after %v along with the waiting message", elapsed)
+	}
+	if err := <-waiting; err != nil {
+		t.Fatal(err)
+	}
+}
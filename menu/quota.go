@@ -0,0 +1,71 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	quotaKey   = "quota_exceeded"
+	quotaAlert = "You have reached the limit of your plan"
+)
+
+/*
+	A checker of limits (e.g. of a subscription plan) that is consulted before endpoints are called
+	The path of a node is relative to the root (see Menu.Find)
+*/
+type QuotaChecker interface {
+	Allow(userID int, path string) bool
+}
+
+/*
+	Sets a checker of limits that decides whether a user may call an endpoint,
+	so plan limits are enforced in one place instead of every endpoint
+	A call over the limit displays the upsell page (see WithUpsell) or an alert localized by "<flow id>/quota_exceeded"
+*/
+func (f *Menu) WithQuota(q QuotaChecker) *Menu {
+	f.quota = q
+	return f
+}
+
+/*
+	Sets a node whose page is displayed instead of calling an endpoint over the limit (e.g. a list of plans)
+	A node without a page is opened at the page of its parent
+*/
+func (f *Menu) WithUpsell(node *Node) *Menu {
+	f.upsell = node
+	return f
+}
+
+/*
+	Checks the quota for the node's endpoint and takes the user to the upsell page if it is exceeded
+	Returns false if the endpoint may be called
+*/
+func (e *Node) overQuota(c *tb.Callback, d *Dialog) bool {
+	f := e.flow
+	if f.quota == nil || c.Sender == nil || f.quota.Allow(c.Sender.ID, e.relativePath()) {
+		return false
+	}
+	if page := f.upsell; page != nil {
+		if !page.HasPage() && page.prev != nil {
+			page = page.prev
+		}
+		f.settle(c, 0)
+		if err := e.update(c.Sender, d, page); err != nil {
+			f.fail(err, c, e)
+		}
+		return true
+	}
+	text := f.translate(d.Language, quotaKey, quotaAlert)
+	var err error
+	if c.ID == "" {
+		// reply keyboard presses can not be answered with an alert
+		f.pace(c.Sender)
+		_, err = f.bot.Send(c.Sender, text)
+	} else {
+		err = f.respond(c, &tb.CallbackResponse{Text: text, ShowAlert: true})
+	}
+	if err != nil {
+		f.fail(err, c, e)
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const radioMarker = "🔘 "
+
+/*
+	A group of sibling nodes where only one option can be selected at a time
+	The selected option is stored in the dialog and is marked in the menu
+*/
+type RadioGroup struct {
+	key      string
+	options  []*Node
+	onChange func(e *Node, c *tb.Callback, value string)
+}
+
+/*
+	Adds a group of exclusive options to the current node
+	Pressing an option selects it, deselects the others and calls the callback with the option's text
+	Returns the new group
+*/
+func (e *Node) AddRadioGroup(options []string, onChange func(e *Node, c *tb.Callback, value string)) *RadioGroup {
+	g := &RadioGroup{options: make([]*Node, len(options)), onChange: onChange}
+	for i, option := range options {
+		node := e.AddSub(option, g.choose)
+		node.marker = g.marker(node)
+		g.options[i] = node
+	}
+	if len(g.options) > 0 {
+		g.key = "radio_" + g.options[0].id
+	}
+	return g
+}
+
+/*
+	Get option nodes of the group
+*/
+func (g *RadioGroup) GetOptions() []*Node {
+	return g.options
+}
+
+/*
+	Gets the text of the option selected in a dialog
+	Returns false if nothing is selected
+*/
+func (g *RadioGroup) Selected(d *Dialog) (string, bool) {
+	if value, ok := d.Get(g.key); ok {
+		for _, option := range g.options {
+			if option.id == value.(string) {
+				return option.text, true
+			}
+		}
+	}
+	return "", false
+}
+
+/*
+	Selects an option in a dialog without calling the callback
+	The change is displayed the next time the menu is updated
+*/
+func (g *RadioGroup) Select(d *Dialog, value string) {
+	for _, option := range g.options {
+		if option.text == value {
+			d.Set(g.key, option.id)
+			d.setDirty(true)
+			return
+		}
+	}
+}
+
+/*
+	Creates a marker function that marks an option when it is selected
+*/
+func (g *RadioGroup) marker(option *Node) func(d *Dialog) string {
+	return func(d *Dialog) string {
+		if value, ok := d.Get(g.key); ok && value.(string) == option.id {
+			return radioMarker
+		}
+		return ""
+	}
+}
+
+/*
+	Endpoint for the options that moves the selection
+*/
+func (g *RadioGroup) choose(e *Node, c *tb.Callback) int {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return Stay
+	}
+	if value, ok := d.Get(g.key); ok && value.(string) == e.id {
+		// already selected
+		return Stay
+	}
+	d.Set(g.key, e.id)
+	d.setDirty(true)
+	if g.onChange != nil {
+		g.onChange(e, c, e.text)
+	}
+	return Forward
+}
@@ -0,0 +1,104 @@
+package menu
+
+import (
+	"time"
+)
+
+/*
+	Makes dialogs that are on the node's page be updated periodically (e.g. live scores or countdowns)
+	The refresher is called before every update, so it can change the caption (see Dialog.SetCaption)
+	or the values that the buttons are labeled by, updating stops when the dialog leaves the page
+	Returns the current node
+*/
+func (e *Node) AutoRefresh(interval time.Duration, refresher func(e *Node, d *Dialog)) *Node {
+	e.refreshEvery = interval
+	e.refresher = refresher
+	return e
+}
+
+/*
+	Sets a caption of a node's page in the dialog, it replaces the caption every time the dialog comes to the page
+	(e.g. by going back to it), so the page gets its own caption back whatever was displayed on other pages
+	The caption of the current page is displayed by the next update, an empty text removes the caption of the page
+*/
+func (d *Dialog) SetCaption(node *Node, text string) {
+	d.mx.Lock()
+	if text == "" {
+		delete(d.captions, node.id)
+	} else {
+		if d.captions == nil {
+			d.captions = make(map[string]string)
+		}
+		d.captions[node.id] = text
+	}
+	current := d.Position == node
+	d.mx.Unlock()
+	if current && text != "" && d.setText(text) {
+		d.setDirty(true)
+	}
+}
+
+/*
+	Gets a caption of a node's page in the dialog
+*/
+func (d *Dialog) pageCaption(page *Node) (string, bool) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	text, ok := d.captions[page.id]
+	return text, ok
+}
+
+/*
+	Starts updating the dialog while it is on the node's page unless it is being updated already
+*/
+func (e *Node) autoRefresh(d *Dialog) {
+	if e.refreshEvery <= 0 || e.refresher == nil {
+		return
+	}
+	d.mx.Lock()
+	if d.refreshing == e {
+		d.mx.Unlock()
+		return
+	}
+	d.refreshing = e
+	d.mx.Unlock()
+	go e.refresh(d)
+}
+
+/*
+	Updates the dialog every interval until it leaves the node's page or is closed
+	Updates are made in turn with taps of the dialog, so the refresher does not see a page that is being changed
+*/
+func (e *Node) refresh(d *Dialog) {
+	ticker := time.NewTicker(e.refreshEvery)
+	defer ticker.Stop()
+	defer func() {
+		d.mx.Lock()
+		if d.refreshing == e {
+			d.refreshing = nil
+		}
+		d.mx.Unlock()
+	}()
+	done := d.done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		left := false
+		d.exclusive(func() {
+			if d.Position != e || d.Message == nil {
+				left = true
+				return
+			}
+			e.refresher(e, d)
+			if err := e.update(d.Message.Chat, d, e); err != nil {
+				e.flow.fail(err, nil, e)
+			}
+		})
+		if left {
+			return
+		}
+	}
+}
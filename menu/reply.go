@@ -0,0 +1,177 @@
+package menu
+
+import (
+	"github.com/tucnak/tr"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A flow that displays its pages as a reply keyboard at the bottom of the chat instead of inline buttons
+	The tree, localization and dialogs are the same as of a regular menu,
+	though every page is sent as a new message, since a reply keyboard can not be edited
+	Text messages have to be passed to ReplyMenu.Process for the buttons to be handled
+*/
+type ReplyMenu struct {
+	*Menu
+}
+
+/*
+	Creates a new reply keyboard flow
+	The same rules as for NewMenuFlow apply to the id
+*/
+func NewReplyMenuFlow(id string, bot Bot, engine *tr.Engine) (*ReplyMenu, error) {
+	f, err := NewMenuFlow(id, bot, engine)
+	if err != nil {
+		return nil, err
+	}
+	f.reply = true
+	return &ReplyMenu{Menu: f}, nil
+}
+
+/*
+	Process a text message from a user
+	The text is matched against labels of the buttons on the page the user is on,
+	a matching node is handled as if its button was pressed
+	An endpoint receives a callback with the sender and the last menu message only
+	Returns true only if the message was consumed by the menu
+*/
+func (f *ReplyMenu) Process(m *tb.Message) bool {
+	if f.Menu.Process(m) {
+		return true
+	}
+	if m == nil || m.Sender == nil || len(m.Text) < 1 || f.gate.closed() {
+		return false
+	}
+	d, ok := f.DialogOfMessage(m)
+	if !ok {
+		return false
+	}
+	consumed := false
+	// presses are handled in turn with other updates of the dialog the same way as callbacks
+	d.exclusive(func() {
+		consumed = f.pressText(m, d)
+	})
+	return consumed
+}
+
+/*
+	Handles a text as a press of a button with the same label on the page of the dialog
+*/
+func (f *ReplyMenu) pressText(m *tb.Message, d *Dialog) bool {
+	if d.Position == nil {
+		return false
+	}
+	page := d.Position
+	if action, ok := page.action(d, m.Text); ok {
+		d.touch()
+		if err := action(m.Sender, d); err != nil {
+			f.fail(err, nil, page)
+		}
+		return true
+	}
+	nodes, buttons := page.display(d)
+	for i, btn := range buttons {
+		if btn.Text != m.Text || btn.URL != "" || btn.InlineQuery != "" {
+			continue
+		}
+		nodes[i].press(&tb.Callback{Sender: m.Sender, Message: d.Message})
+		return true
+	}
+	return false
+}
+
+/*
+	Removes the keyboard from a user with a farewell text and deletes the session
+	The keyboard stays if the text is empty, since Telegram does not accept empty messages
+*/
+func (f *ReplyMenu) Stop(to tb.Recipient, text, lang string) error {
+	if d, ok := f.GetDialog(to.Recipient()); ok {
+		f.dropDialog(to.Recipient(), d)
+	}
+	if text == "" {
+		return nil
+	}
+	f.pace(to)
+	_, err := f.bot.Send(to, text, &tb.ReplyMarkup{ReplyKeyboardRemove: true})
+	return err
+}
+
+/*
+	Finds a handler of an auxiliary button on the node's page by its text
+*/
+func (e *Node) action(d *Dialog, text string) (func(to tb.Recipient, d *Dialog) error, bool) {
+	markup := e.markup(d)
+	e.flow.tree.RLock()
+	defer e.flow.tree.RUnlock()
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Text != text || btn.Unique == "" {
+				continue
+			}
+			for action, control := range e.controls {
+				if control.Data == btn.Data {
+					return e.actions[action], true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+/*
+	Handles the node as if its button was pressed
+	The press is admitted the same way as a callback, refusals are sent as messages
+*/
+func (e *Node) press(c *tb.Callback) {
+	d, ok := e.acceptDeferred(c)
+	if !ok {
+		return
+	}
+	if e.endpoint != nil && e.input == nil {
+		// the call records the tap itself
+		e.call(c, d)
+		return
+	}
+	audited := e.flow.auditTap(e, c, d)
+	var err error
+	result := AuditInput
+	if e.input != nil {
+		err = e.await(c.Sender, d)
+	} else {
+		d.cancelInput()
+		err = e.next(c.Sender, d)
+		result = AuditForward
+	}
+	if err != nil {
+		e.flow.fail(err, c, e)
+	}
+	audited(d, result, err)
+}
+
+/*
+	Converts a markup of inline buttons to a reply keyboard for reply flows
+	Links and inline queries can not be opened from a reply keyboard, so they are left out
+	A missing markup removes the keyboard
+*/
+func (f *Menu) keyboard(markup *tb.ReplyMarkup) *tb.ReplyMarkup {
+	if !f.reply {
+		return markup
+	}
+	if markup == nil {
+		return &tb.ReplyMarkup{ReplyKeyboardRemove: true}
+	}
+	rows := make([][]tb.ReplyButton, 0, len(markup.InlineKeyboard))
+	for _, row := range markup.InlineKeyboard {
+		buttons := make([]tb.ReplyButton, 0, len(row))
+		for _, btn := range row {
+			if btn.URL != "" || btn.InlineQuery != "" {
+				continue
+			}
+			buttons = append(buttons, tb.ReplyButton{Text: btn.Text})
+		}
+		if len(buttons) > 0 {
+			rows = append(rows, buttons)
+		}
+	}
+	return &tb.ReplyMarkup{ReplyKeyboard: rows, ResizeReplyKeyboard: true}
+}
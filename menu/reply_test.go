@@ -0,0 +1,93 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"go-telegram-flow/menu/menutest"
+	"sync"
+	"testing"
+
+	"github.com/tucnak/tr"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Creates a reply keyboard flow with a page of two items that count their calls
+*/
+func newReplyFlow(t *testing.T, calls *int, mx *sync.Mutex) (*menu.ReplyMenu, *menutest.Bot) {
+	t.Helper()
+	// the test locales are loaded along with the first flow
+	newFlow(t, "locales")
+	bot := menutest.NewBot()
+	flow, err := menu.NewReplyMenuFlow("reply", bot, tr.DefaultEngine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := func(e *menu.Node, c *tb.Callback) int {
+		mx.Lock()
+		*calls++
+		mx.Unlock()
+		return menu.Stay
+	}
+	flow.GetRoot().AddSub("list", nil).Add("open", count).Add("closed", count)
+	flow.Build("en")
+	return flow, bot
+}
+
+func TestReplyPressIsAdmitted(t *testing.T) {
+	var mx sync.Mutex
+	calls := 0
+	flow, bot := newReplyFlow(t, &calls, &mx)
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "reply", "en", flow.Find("list")); err != nil {
+		t.Fatal(err)
+	}
+	flow.Find("list/closed").SetEnabled(&tb.Callback{Sender: user}, false)
+	sent := len(bot.Sends)
+	if !flow.Process(&tb.Message{Sender: user, Chat: &tb.Chat{ID: 1}, Text: "reply/list/closed"}) {
+		t.Fatal("the press is not consumed")
+	}
+	if calls != 0 || len(bot.Sends) != sent+1 {
+		t.Fatalf("the refused press is handled with %d calls and %d messages", calls, len(bot.Sends)-sent)
+	}
+}
+
+func TestReplyPressesDuringBroadcast(t *testing.T) {
+	var mx sync.Mutex
+	calls := 0
+	flow, _ := newReplyFlow(t, &calls, &mx)
+	for i := 1; i <= concurrentUsers; i++ {
+		if err := flow.StartAt(&tb.User{ID: i}, "reply", "en", flow.Find("list")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				flow.BroadcastRefresh(nil)
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 1; i <= concurrentUsers; i++ {
+		wg.Add(1)
+		go func(user *tb.User) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if !flow.Process(&tb.Message{Sender: user, Chat: &tb.Chat{ID: int64(user.ID)}, Text: "reply/list/open"}) {
+					t.Error("the press is not consumed")
+				}
+			}
+		}(&tb.User{ID: i})
+	}
+	wg.Wait()
+	close(done)
+	<-stopped
+	if calls != concurrentUsers*5 {
+		t.Fatalf("the endpoint is called %d times instead of %d", calls, concurrentUsers*5)
+	}
+}
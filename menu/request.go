@@ -0,0 +1,116 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	locationRequest = "location"
+	contactRequest  = "contact"
+)
+
+/*
+	Makes the node ask a user for a location when pressed
+	The prompt is sent with a one-time keyboard that has a button sharing the location,
+	the received location is stored in the dialog (see GetLocation) and passed to the handler,
+	then the menu is sent anew below the answer
+	Messages with locations have to be passed to Menu.Process (e.g. from an OnLocation handler)
+	The result of the handler is treated the same way as for AwaitText
+	Returns the current node
+*/
+func (e *Node) AwaitLocation(prompt, button string, handler func(e *Node, m *tb.Message) int) *Node {
+	e.input = &textInput{prompt: prompt, handler: handler, request: locationRequest, button: button}
+	return e
+}
+
+/*
+	Makes the node ask a user for a phone number the same way as AwaitLocation
+	The received contact is stored in the dialog (see GetContact)
+	Messages with contacts have to be passed to Menu.Process (e.g. from an OnContact handler)
+	Returns the current node
+*/
+func (e *Node) AwaitContact(prompt, button string, handler func(e *Node, m *tb.Message) int) *Node {
+	e.input = &textInput{prompt: prompt, handler: handler, request: contactRequest, button: button}
+	return e
+}
+
+/*
+	Gets the location that a user has shared with the node in a dialog
+*/
+func (e *Node) GetLocation(d *Dialog) (*tb.Location, bool) {
+	value, ok := d.Get(locationRequest + "_" + e.id)
+	if !ok {
+		return nil, false
+	}
+	return value.(*tb.Location), true
+}
+
+/*
+	Gets the contact that a user has shared with the node in a dialog
+*/
+func (e *Node) GetContact(d *Dialog) (*tb.Contact, bool) {
+	value, ok := d.Get(contactRequest + "_" + e.id)
+	if !ok {
+		return nil, false
+	}
+	return value.(*tb.Contact), true
+}
+
+/*
+	Sends the prompt of a location or a contact request with its keyboard
+*/
+func (e *Node) request(to tb.Recipient, d *Dialog) error {
+	btn := tb.ReplyButton{
+		Text:     e.input.button,
+		Location: e.input.request == locationRequest,
+		Contact:  e.input.request == contactRequest,
+	}
+	markup := &tb.ReplyMarkup{
+		ReplyKeyboard:       [][]tb.ReplyButton{{btn}},
+		ResizeReplyKeyboard: true,
+		OneTimeKeyboard:     true,
+	}
+	e.flow.pace(to)
+	msg, err := e.flow.bot.Send(to, e.input.prompt, markup)
+	if err != nil {
+		return err
+	}
+	d.input = e
+	d.prompt = msg
+	return nil
+}
+
+/*
+	Stores a shared location or contact in the dialog
+*/
+func (in *textInput) capture(e *Node, d *Dialog, m *tb.Message) {
+	if m.Location != nil {
+		d.Set(locationRequest+"_"+e.id, m.Location)
+	}
+	if m.Contact != nil {
+		d.Set(contactRequest+"_"+e.id, m.Contact)
+	}
+}
+
+/*
+	Sends the menu of the dialog anew after a request was answered, so it is displayed below the answer
+	The prompt of the request is deleted
+*/
+func (f *Menu) resendMenu(to tb.Recipient, d *Dialog, page *Node) error {
+	if d.prompt != nil {
+		f.bot.Delete(d.prompt)
+		d.prompt = nil
+	}
+	prev := d.Position
+	d.Position = page
+	msg, err := f.send(to, d)
+	if err != nil {
+		d.Position = prev
+		return err
+	}
+	f.bot.Delete(d.Message)
+	f.setMessage(d, msg)
+	d.record(prev, page)
+	f.navigated(prev, page, d)
+	return nil
+}
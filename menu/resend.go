@@ -0,0 +1,85 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+	"time"
+)
+
+/*
+	Makes the menu send a fresh message when the menu message can not be edited anymore
+	(e.g. a user has deleted it), so the dialog continues in the new message
+*/
+func (f *Menu) WithResendOnEditFailure(resend bool) *Menu {
+	f.resend = resend
+	return f
+}
+
+/*
+	Edits the menu message of the dialog with a specified markup
+	Sends a new message instead if the old one is gone and resending is enabled
+	An edit that would not change the message counts as a successful one
+	Only internal use is intended
+*/
+func (f *Menu) edit(d *Dialog, markup *tb.ReplyMarkup, options ...interface{}) (*tb.Message, error) {
+	d.edits++
+	return f.tryEdit(d, d.edits, markup, 0, false, options)
+}
+
+/*
+	Makes an attempt of an edit of the menu message
+	An edit rejected by flood control is retried once after the time Telegram asked to wait,
+	other transient failures are retried with a backoff (see WithEditRetry)
+	Retries are scheduled in turn with other updates of the dialog instead of holding the turn while waiting,
+	the edit counts as a successful one meanwhile and its retry is dropped if the dialog is edited again
+*/
+func (f *Menu) tryEdit(d *Dialog, serial uint64, markup *tb.ReplyMarkup, attempt int, flooded bool, options []interface{}) (*tb.Message, error) {
+	started := time.Now()
+	msg, err := f.editMessage(d, markup, options...)
+	f.reportEdited(started, err)
+	delay, again := retry(err)
+	if again = again && !flooded && d.Message.Chat != nil; again {
+		flooded = true
+		f.limiter.block(d.Message.Chat.ID, delay)
+	} else if again = transient(err) && attempt < f.editRetries(); again {
+		delay = f.backoff(attempt)
+		attempt++
+	}
+	if again {
+		d.after(delay, func() {
+			if d.edits != serial {
+				// the newer edit displays the dialog
+				return
+			}
+			msg, err := f.tryEdit(d, serial, markup, attempt, flooded, options)
+			if err != nil {
+				f.fail(err, nil, d.Position)
+			} else if !d.isClosed() {
+				f.setMessage(d, msg)
+			}
+		})
+		return d.Message, nil
+	}
+	if notModified(err) {
+		// the menu already displays the state, so the edit has done its job
+		return d.Message, nil
+	}
+	if err == nil || !f.resend || markup == nil || !messageGone(err) || d.Message.Chat == nil {
+		return msg, err
+	}
+	return f.send(d.Message.Chat, d)
+}
+
+/*
+	Checks if an edit failed because the message does not exist anymore
+*/
+func messageGone(err error) bool {
+	return strings.Contains(err.Error(), "message to edit not found")
+}
+
+/*
+	Checks if an edit failed because the message already has the same content and markup
+*/
+func notModified(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message is not modified")
+}
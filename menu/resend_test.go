@@ -0,0 +1,54 @@
+package menu_test
+
+import (
+	"errors"
+	"go-telegram-flow/menu/menutest"
+	"sync"
+	"testing"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	A fake bot that fails a number of edits with a server error
+*/
+type flakyBot struct {
+	*menutest.Bot
+	fails int
+	mx    sync.Mutex
+}
+
+/*
+	Fails the edit if there are failures left, otherwise records it
+*/
+func (b *flakyBot) Edit(m tb.Editable, what interface{}, options ...interface{}) (*tb.Message, error) {
+	b.mx.Lock()
+	fail := b.fails > 0
+	b.fails--
+	b.mx.Unlock()
+	if fail {
+		return nil, errors.New("telegram: Bad Gateway (502)")
+	}
+	return b.Bot.Edit(m, what, options...)
+}
+
+func TestEditRetryReleasesTurn(t *testing.T) {
+	bot := &flakyBot{Bot: menutest.NewBot()}
+	flow := newFlowOn(t, "retry", bot)
+	save := flow.GetRoot().AddSub("settings", nil).Add("save", stay)
+	flow.WithEditRetry(1, 200*time.Millisecond).Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.Start(user, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	bot.fails = 1
+	started := time.Now()
+	if err := bot.Tap(flow, user, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed >= 100*time.Millisecond {
+		t.Fatalf("the tap waits %v for the retry of its edit", elapsed)
+	}
+	waitButton(t, bot.Bot, user, save)
+}
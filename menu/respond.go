@@ -0,0 +1,180 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	Makes endpoints of all nodes responsible for responding to their callbacks,
+	so they can answer with a custom text or a URL even after the endpoint has returned
+	Callbacks that are not responded within the timeout get a bare response
+	Zero timeout responds right after the endpoint
+*/
+func (f *Menu) WithManualRespond(timeout time.Duration) *Menu {
+	f.respondTimeout = timeout
+	return f
+}
+
+/*
+	Makes the node's endpoint responsible for responding to its callbacks the same way as WithManualRespond
+	It overrides the timeout of the flow for the node
+	Returns the current node
+*/
+func (e *Node) SetManualRespond(timeout time.Duration) *Node {
+	e.respondIn = timeout
+	return e
+}
+
+/*
+	Responds to the callback with a custom response
+	Only the first answer to a callback is sent
+*/
+func (e *Node) Respond(c *tb.Callback, response ...*tb.CallbackResponse) error {
+	return e.flow.respond(c, response...)
+}
+
+/*
+	Answers the callback with an alert that has to be dismissed by the user
+	The text is localized by "<flow id>/<text>" if there is such a key, otherwise it is displayed as is
+	Only the first answer to a callback is sent
+*/
+func (e *Node) Alert(c *tb.Callback, text string) error {
+	return e.answer(c, text, true)
+}
+
+/*
+	Answers the callback with a notification that disappears by itself
+	The text is localized the same way as for Alert
+	Only the first answer to a callback is sent
+*/
+func (e *Node) Toast(c *tb.Callback, text string) error {
+	return e.answer(c, text, false)
+}
+
+/*
+	Answers the callback with a localized text
+*/
+func (e *Node) answer(c *tb.Callback, text string, alert bool) error {
+	lang := e.flow.languageOf(c.Sender)
+	if d, ok := e.flow.dialog(c); ok {
+		lang = d.Language
+	}
+	return e.flow.respond(c, &tb.CallbackResponse{Text: e.flow.translate(lang, text, text), ShowAlert: alert})
+}
+
+/*
+	Responds to a callback unless it has been responded already
+	Only internal use is intended
+*/
+func (f *Menu) respond(c *tb.Callback, response ...*tb.CallbackResponse) error {
+	if c.ID == "" || !f.claim(c) {
+		return nil
+	}
+	return f.bot.Respond(c, response...)
+}
+
+/*
+	A time for which a settled callback is remembered as answered,
+	so late answers of its endpoint are not sent twice
+*/
+const answerLifetime = time.Minute
+
+/*
+	An answer to a callback that is handled by an endpoint
+*/
+type answer struct {
+	settled int64
+	claimed int32
+}
+
+/*
+	Makes the endpoint of a callback responsible for its only answer until the callback is settled
+	Answers of callbacks that have been settled long ago are forgotten once in a while
+	Only internal use is intended
+*/
+func (f *Menu) expectRespond(c *tb.Callback) {
+	if c.ID == "" {
+		return
+	}
+	now := time.Now().UnixNano()
+	if swept := atomic.LoadInt64(&f.answersSwept); now-swept >= int64(answerLifetime) && atomic.CompareAndSwapInt64(&f.answersSwept, swept, now) {
+		f.answered.Range(func(id, value interface{}) bool {
+			if settled := atomic.LoadInt64(&value.(*answer).settled); settled != 0 && now-settled >= int64(answerLifetime) {
+				f.answered.Delete(id)
+			}
+			return true
+		})
+	}
+	f.answered.Store(c.ID, new(answer))
+}
+
+/*
+	Claims the only answer to a callback that is handled by an endpoint
+	Other callbacks are answered once by the flow itself, so they are not tracked
+*/
+func (f *Menu) claim(c *tb.Callback) bool {
+	value, ok := f.answered.Load(c.ID)
+	if !ok {
+		return true
+	}
+	return atomic.CompareAndSwapInt32(&value.(*answer).claimed, 0, 1)
+}
+
+/*
+	Responds to a callback after its endpoint unless the endpoint has responded itself
+	With a timeout the endpoint is given time to respond asynchronously
+	The callback stays claimed afterwards, so the endpoint can not answer it again
+	Only internal use is intended
+*/
+func (f *Menu) settle(c *tb.Callback, timeout time.Duration) {
+	if c.ID == "" {
+		// not a real callback (e.g. a reply keyboard press)
+		return
+	}
+	done := func() {
+		if f.claim(c) {
+			f.bareRespond(c)
+		}
+		if value, ok := f.answered.Load(c.ID); ok {
+			atomic.StoreInt64(&value.(*answer).settled, time.Now().UnixNano())
+		}
+	}
+	if timeout <= 0 {
+		done()
+		return
+	}
+	time.AfterFunc(timeout, done)
+}
+
+/*
+	Responds to a callback without any text
+*/
+func (f *Menu) bareRespond(c *tb.Callback) {
+	if err := f.bot.Respond(c); err != nil {
+		f.fail(err, c, nil)
+	}
+}
+
+/*
+	Gets a time that the node's endpoint is given to respond
+*/
+func (e *Node) respondTimeout() time.Duration {
+	if e.respondIn > 0 {
+		return e.respondIn
+	}
+	return e.flow.respondTimeout
+}
+
+/*
+	Translates a key relative to the flow
+	Returns the fallback if there is no translation
+*/
+func (f *Menu) translate(lang, key, fallback string) string {
+	path := f.id + "/" + key
+	if text := f.engine.Lang(f.resolve(lang)).Tr(path); text != "" && text != path {
+		return text
+	}
+	return fallback
+}
@@ -0,0 +1,31 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestLateRespondIsNotSent(t *testing.T) {
+	flow, bot := newFlow(t, "respond")
+	var tapped *tb.Callback
+	late := flow.GetRoot().Add("late", func(e *menu.Node, c *tb.Callback) int {
+		tapped = c
+		return menu.Stay
+	})
+	flow.WithManualRespond(0).Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.Start(user, "caption", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "late"); err != nil {
+		t.Fatal(err)
+	}
+	if err := late.Toast(tapped, "too late"); err != nil {
+		t.Fatal(err)
+	}
+	if len(bot.Responses) != 1 {
+		t.Fatalf("the callback is answered %d times", len(bot.Responses))
+	}
+}
@@ -0,0 +1,118 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	resumeKey    = "resume"
+	continueKey  = "continue"
+	continueText = "Continue"
+	startOverKey = "start_over"
+	startOver    = "Start over"
+)
+
+/*
+	Makes commands bound with BindCommand resume a dialog at the page the user has left it on
+	instead of opening the command's node
+	With a prompt the user is asked first (e.g. "Continue where you left off?"), the prompt is localized
+	by "<flow id>/resume" and the buttons by "<flow id>/continue" and "<flow id>/start_over",
+	an empty prompt resumes right away
+	The prompt adds a hidden node to the root, so it has to be enabled before the flow is built
+*/
+func (f *Menu) WithResume(prompt string) *Menu {
+	f.resume, f.resumePrompt = true, prompt
+	if prompt == "" || f.resumeNode != nil {
+		return f
+	}
+	f.tree.Lock()
+	f.resumeNode = f.root.AddSub(resumeKey, nil).
+		SetVisible(func(e *Node, d *Dialog) bool { return false }).
+		SetLabelFunc(f.labelOf(resumeKey, prompt))
+	f.resumeNode.AddSub(continueKey, f.continueDialog).SetLabelFunc(f.labelOf(continueKey, continueText))
+	f.resumeNode.AddSub(startOverKey, f.startOver).SetLabelFunc(f.labelOf(startOverKey, startOver))
+	f.tree.Unlock()
+	return f
+}
+
+/*
+	Resumes a dialog of the sender of a command or asks whether to resume it
+	Returns false if the dialog has nothing to resume
+*/
+func (f *Menu) resumeDialog(m *tb.Message, d *Dialog, node *Node) (bool, error) {
+	position := d.Position
+	if !f.resume || position == nil || position == node || position == f.root || position == f.resumeNode {
+		return false, nil
+	}
+	lang := f.resolve(d.Language)
+	if f.resumeNode == nil {
+		return true, f.StartAt(m.Sender, d.GetCaption(), lang, position)
+	}
+	d.mx.Lock()
+	last := d.step()
+	d.mx.Unlock()
+	if err := f.StartAt(m.Sender, f.translate(lang, resumeKey, f.resumePrompt), lang, f.resumeNode); err != nil {
+		return true, err
+	}
+	// the prompt is held by a new dialog when every message has a dialog of its own
+	if prompt, ok := f.GetDialog(m.Sender.Recipient()); ok {
+		prompt.mx.Lock()
+		prompt.resumeFrom, prompt.restartAt = &last, node
+		prompt.mx.Unlock()
+	}
+	return true, nil
+}
+
+/*
+	Endpoint of the "continue" button that takes the dialog back to the page it was left on
+*/
+func (f *Menu) continueDialog(e *Node, c *tb.Callback) int {
+	d, ok := f.dialog(c)
+	if !ok {
+		return Stay
+	}
+	d.mx.Lock()
+	last := d.resumeFrom
+	d.resumeFrom, d.restartAt = nil, nil
+	d.mx.Unlock()
+	if last == nil {
+		return Stay
+	}
+	d.text, d.textKey = last.text, last.textKey
+	if err := e.update(c.Sender, d, last.position); err != nil {
+		f.fail(err, c, e)
+	}
+	return Stay
+}
+
+/*
+	Endpoint of the "start over" button that opens the node of the command instead
+*/
+func (f *Menu) startOver(e *Node, c *tb.Callback) int {
+	d, ok := f.dialog(c)
+	if !ok {
+		return Stay
+	}
+	d.mx.Lock()
+	node := d.restartAt
+	d.resumeFrom, d.restartAt = nil, nil
+	d.mx.Unlock()
+	if node == nil {
+		node = f.root
+	}
+	d.history = nil
+	d.setText(f.engine.Lang(f.resolve(d.Language)).Tr(node.path))
+	if err := e.update(c.Sender, d, node); err != nil {
+		f.fail(err, c, e)
+	}
+	return Stay
+}
+
+/*
+	Creates a label function that translates a key relative to the flow
+*/
+func (f *Menu) labelOf(key, fallback string) func(d *Dialog, lang string) string {
+	return func(d *Dialog, lang string) string {
+		return f.translate(lang, key, fallback)
+	}
+}
@@ -0,0 +1,30 @@
+package menu_test
+
+import (
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestResumePerMessage(t *testing.T) {
+	flow, bot := newFlow(t, "resume")
+	flow.WithDialogPerMessage().WithResume("resume?").BindCommand(bot, "/start", nil)
+	flow.GetRoot().AddSub("settings", nil).Add("save", stay)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "resume", "en", flow.GetRoot()); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Command(user, "/start"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Press(user, "Continue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "settings/save"); err != nil {
+		t.Fatalf("the dialog is not resumed at the settings: %v", err)
+	}
+}
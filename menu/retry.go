@@ -0,0 +1,64 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+/*
+	Makes edits of menu messages that fail because of network problems or errors of Telegram servers
+	be retried a number of times with an exponential backoff starting at a specified delay
+	Permanent errors (e.g. "message is not modified") are never retried, zero attempts disable retrying
+*/
+func (f *Menu) WithEditRetry(attempts int, delay time.Duration) *Menu {
+	f.mx.Lock()
+	f.retries, f.retryDelay = attempts, delay
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Gets a random delay before a retry, it doubles with every attempt
+*/
+func (f *Menu) backoff(attempt int) time.Duration {
+	f.mx.RLock()
+	delay := f.retryDelay << uint(attempt)
+	f.mx.RUnlock()
+	if delay <= 0 {
+		return 0
+	}
+	// a jitter keeps many dialogs from retrying at the same moment
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+/*
+	Gets the number of times a failed edit is retried
+*/
+func (f *Menu) editRetries() int {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	return f.retries
+}
+
+/*
+	Checks if an error is worth retrying: a network failure or a server side error of Telegram
+*/
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := errors.Cause(err).(net.Error); ok {
+		return true
+	}
+	text := err.Error()
+	for _, reason := range []string{"(500)", "(502)", "(503)", "(504)", "Internal Server Error", "Bad Gateway",
+		"connection reset", "connection refused", "EOF", "timeout"} {
+		if strings.Contains(text, reason) {
+			return true
+		}
+	}
+	return false
+}
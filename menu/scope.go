@@ -0,0 +1,149 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+)
+
+/*
+	Makes every menu message hold a dialog of its own, so a user can use several menus of the flow at once
+	Starting a menu does not replace the previous one,
+	methods that take a recipient operate on the dialog that the user has used most recently
+	Must be set before any menu is started
+*/
+func (f *Menu) WithDialogPerMessage() *Menu {
+	f.perMessage = true
+	return f
+}
+
+/*
+	Makes every user of a group chat have a dialog of their own, keyed by the chat and the user
+	Menus in group chats have to be started with StartFor
+	If copy is true a user pressing a menu of another user gets a fresh copy of the menu,
+	otherwise the press is rejected with an alert
+*/
+func (f *Menu) WithGroupDialogs(copy bool) *Menu {
+	f.groups = true
+	f.copyForeign = copy
+	return f
+}
+
+/*
+	Sends a new instance of a menu to a chat for a specified user
+	Tries to delete the old menu of the user in the chat before sending a new one
+*/
+func (f *Menu) StartFor(chat *tb.Chat, user *tb.User, text, lang string) error {
+	return f.start(chat, f.userKey(chat, user), user.Recipient(), text, lang, f.root, true)
+}
+
+/*
+	Retrieves a dialog that a callback belongs to
+	Prefer it over GetDialog in endpoints, since dialogs are not always keyed by the sender
+*/
+func (f *Menu) DialogOf(c *tb.Callback) (*Dialog, bool) {
+	return f.dialog(c)
+}
+
+/*
+	Retrieves a dialog of the sender of a text message
+*/
+func (f *Menu) DialogOfMessage(m *tb.Message) (*Dialog, bool) {
+	return f.GetDialog(f.userKey(m.Chat, m.Sender))
+}
+
+/*
+	Retrieves a dialog that a callback belongs to
+	Only internal use is intended
+*/
+func (f *Menu) dialog(c *tb.Callback) (*Dialog, bool) {
+	if c.Message == nil {
+		return f.GetDialog(c.Sender.Recipient())
+	}
+	if f.perMessage && c.Message.Chat != nil {
+		return f.GetDialog(messageKey(c.Message))
+	}
+	return f.GetDialog(f.userKey(c.Message.Chat, c.Sender))
+}
+
+/*
+	Gets an identificator of a dialog of a user in a chat
+	Dialogs are keyed by the user only, unless group dialogs are enabled
+*/
+func (f *Menu) userKey(chat *tb.Chat, user *tb.User) string {
+	if f.groups && chat != nil && chat.ID != int64(user.ID) {
+		return strconv.FormatInt(chat.ID, 10) + "/" + user.Recipient()
+	}
+	return user.Recipient()
+}
+
+/*
+	Handles a press of a menu that belongs to another user of a group chat
+	Returns false if the menu belongs to the user
+*/
+func (f *Menu) foreign(c *tb.Callback, d *Dialog, ok bool) bool {
+	if !f.groups || c.Message == nil || c.Message.Chat == nil || c.Message.Chat.ID == int64(c.Sender.ID) {
+		return false
+	}
+	if ok && d.Message != nil && d.Message.ID == c.Message.ID {
+		return false
+	}
+	if !f.copyForeign {
+		err := f.bot.Respond(c, &tb.CallbackResponse{Text: f.notYours(f.languageOf(c.Sender)), ShowAlert: true})
+		if err != nil {
+			f.fail(err, c, nil)
+		}
+		return true
+	}
+	if err := f.bot.Respond(c); err != nil {
+		f.fail(err, c, nil)
+		return true
+	}
+	caption := c.Message.Text
+	if isMedia(c.Message) {
+		caption = c.Message.Caption
+	}
+	if err := f.StartFor(c.Message.Chat, c.Sender, caption, f.languageOf(c.Sender)); err != nil {
+		f.fail(err, c, nil)
+	}
+	return true
+}
+
+/*
+	Sets a new menu message of the dialog and keeps the dialog reachable by the message
+	Only internal use is intended
+*/
+func (f *Menu) setMessage(d *Dialog, msg *tb.Message) {
+	d.mx.Lock()
+	prev := d.Message
+	d.Message = msg
+	d.mx.Unlock()
+	if !f.perMessage {
+		return
+	}
+	if prev != nil && prev.Chat != nil {
+		f.deleteDialog(messageKey(prev))
+	}
+	if msg != nil && msg.Chat != nil {
+		f.setDialog(messageKey(msg), d)
+	}
+}
+
+/*
+	Deletes a dialog of a user along with its message reference and the cooldowns of the user
+	Only internal use is intended
+*/
+func (f *Menu) dropDialog(id string, d *Dialog) {
+	d.close()
+	f.deleteDialog(id)
+	f.cooldowns.drop(d.GetOwner())
+	if f.perMessage && d.Message != nil && d.Message.Chat != nil {
+		f.deleteDialog(messageKey(d.Message))
+	}
+}
+
+/*
+	Gets an identificator of a dialog by its menu message
+*/
+func messageKey(m *tb.Message) string {
+	return strconv.FormatInt(m.Chat.ID, 10) + "/" + strconv.Itoa(m.ID)
+}
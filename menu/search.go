@@ -0,0 +1,108 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+/*
+	Finds nodes of the tree whose localized label or path contains a query, ignoring the case
+	Nodes are returned in the order of the tree, provided nodes and nodes on hidden branches are not searched
+*/
+func (f *Menu) Search(query, lang string) []*Node {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	lang = f.resolve(lang)
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	found := make([]*Node, 0)
+	f.root.walk(func(e *Node) {
+		if e.prev == nil || e.search || e.onHiddenBranch() {
+			return
+		}
+		if strings.Contains(strings.ToLower(e.label(lang)), query) ||
+			strings.Contains(strings.ToLower(e.relativePath()), query) {
+			found = append(found, e)
+		}
+	})
+	return found
+}
+
+/*
+	Adds a new node that asks for a query and displays the nodes found by it (see Menu.Search) on its page,
+	pressing a found node takes the dialog to its page, or to the page of its parent if it has no page
+	The labels of the found nodes are their localized paths
+	Returns the new node
+*/
+func (e *Node) AddSearch(text, prompt string) *Node {
+	node := e.AddSub(text, nil)
+	node.search = true
+	key := "search_" + node.id
+	node.AwaitText(prompt, func(e *Node, m *tb.Message) int {
+		if d, ok := e.flow.DialogOfMessage(m); ok {
+			d.Set(key, m.Text)
+		}
+		return Forward
+	})
+	node.SetProvider(NodeProviderFunc(func(e *Node, d *Dialog) []*Node {
+		value, _ := d.Get(key)
+		query, _ := value.(string)
+		lang := e.flow.resolve(d.Language)
+		found := e.flow.Search(query, lang)
+		nodes := make([]*Node, 0, len(found))
+		for _, match := range found {
+			if !match.isReachable(d) || !match.isAuthorized(d) {
+				continue
+			}
+			label := match.label(lang)
+			if trail := match.trail(lang); trail != "" {
+				label = trail + " › " + label
+			}
+			nodes = append(nodes, e.flow.NewNode(label, Navigating(jump(match))))
+		}
+		return nodes
+	}))
+	return node
+}
+
+/*
+	Checks if the node or any of its ancestors is hidden from every dialog
+*/
+func (e *Node) onHiddenBranch() bool {
+	for node := e; node != nil; node = node.prev {
+		if node.hidden {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Creates an endpoint that takes the dialog to a found node
+	The jump is checked the same way as a tap on the node's button
+*/
+func jump(target *Node) NavCallback {
+	return func(e *Node, c *tb.Callback) NavigationResult {
+		if d, ok := e.flow.dialog(c); ok && !target.mayJump(c, d) {
+			return Nav().Stay()
+		}
+		return Nav().GotoNode(target)
+	}
+}
+
+/*
+	Checks if the user may be taken to the node: the user is authorized to use it and its guard passes
+	Refused jumps are answered with an alert
+*/
+func (e *Node) mayJump(c *tb.Callback, d *Dialog) bool {
+	if e.flow.authorize != nil && c.Sender != nil && !e.flow.authorize(c.Sender.ID, e) {
+		text := e.flow.translate(d.Language, deniedAlertKey, deniedAlert)
+		if err := e.flow.respond(c, &tb.CallbackResponse{Text: text, ShowAlert: true}); err != nil {
+			e.flow.fail(err, c, e)
+		}
+		return false
+	}
+	return !e.guarded(c, d)
+}
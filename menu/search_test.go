@@ -0,0 +1,41 @@
+package menu_test
+
+import (
+	"go-telegram-flow/menu"
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestSearchSkipsHiddenBranches(t *testing.T) {
+	flow, _ := newFlow(t, "search")
+	flow.GetRoot().AddSub("secret", nil).SetHidden(true).Add("vault", stay)
+	flow.Build("en")
+	if found := flow.Search("vault", "en"); len(found) != 0 {
+		t.Fatalf("found %d nodes below a hidden page", len(found))
+	}
+}
+
+func TestSearchJumpIsGuarded(t *testing.T) {
+	flow, bot := newFlow(t, "jump")
+	root := flow.GetRoot()
+	find := root.AddSearch("find", "query")
+	root.AddSub("orders", nil).Add("list", stay).SetGuard(func(e *menu.Node, c *tb.Callback) bool { return false })
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "jump", "en", root); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "find"); err != nil {
+		t.Fatal(err)
+	}
+	if !flow.Process(&tb.Message{Sender: user, Chat: &tb.Chat{ID: int64(user.ID)}, Text: "orders"}) {
+		t.Fatal("the query is not consumed by the search")
+	}
+	if err := bot.Press(user, "jump/orders"); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := flow.GetDialog(user.Recipient()); !ok || d.Position != find {
+		t.Fatal("the search has jumped past the guard of the found node")
+	}
+}
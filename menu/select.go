@@ -0,0 +1,135 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	selectDone     = "done"
+	selectedMarker = "✅ "
+)
+
+/*
+	A group of sibling nodes that act as toggles
+	Selected options are stored in the dialog and are marked in the menu
+*/
+type SelectGroup struct {
+	key     string
+	options []*Node
+	done    *Node
+}
+
+/*
+	Adds a group of toggle options and a "Done" node to the current node
+	The label of the Done node is shared and localized by the "<flow id>/done" key
+	When Done is pressed the callback receives the selected options in the order they were added,
+	the result of the callback is treated as a result of a node's callback
+	Returns the new group
+*/
+func (e *Node) AddSelectGroup(options []string, onDone func(e *Node, c *tb.Callback, selected []string) int) *SelectGroup {
+	g := &SelectGroup{options: make([]*Node, len(options))}
+	for i, option := range options {
+		node := e.AddSub(option, g.toggle)
+		node.marker = g.marker(node)
+		g.options[i] = node
+	}
+	g.done = e.AddSub(selectDone, func(e *Node, c *tb.Callback) int {
+		d, ok := e.flow.dialog(c)
+		if !ok {
+			return Stay
+		}
+		return onDone(e, c, g.Selected(d))
+	})
+	g.done.labelKey = selectDone
+	g.key = "select_" + g.done.id
+	return g
+}
+
+/*
+	Get option nodes of the group
+*/
+func (g *SelectGroup) GetOptions() []*Node {
+	return g.options
+}
+
+/*
+	Get the Done node of the group
+*/
+func (g *SelectGroup) GetDone() *Node {
+	return g.done
+}
+
+/*
+	Gets texts of the options selected in a dialog
+*/
+func (g *SelectGroup) Selected(d *Dialog) []string {
+	set := g.selection(d)
+	selected := make([]string, 0, len(set))
+	for _, option := range g.options {
+		if set[option.id] {
+			selected = append(selected, option.text)
+		}
+	}
+	return selected
+}
+
+/*
+	Checks if an option is selected in a dialog
+*/
+func (g *SelectGroup) IsSelected(d *Dialog, option *Node) bool {
+	return g.selection(d)[option.id]
+}
+
+/*
+	Clears the selection of a dialog
+*/
+func (g *SelectGroup) Reset(d *Dialog) {
+	d.Delete(g.key)
+	d.setDirty(true)
+}
+
+/*
+	Gets a set of selected option ids
+*/
+func (g *SelectGroup) selection(d *Dialog) map[string]bool {
+	if value, ok := d.Get(g.key); ok {
+		return value.(map[string]bool)
+	}
+	return nil
+}
+
+/*
+	Creates a marker function that marks an option when it is selected
+*/
+func (g *SelectGroup) marker(option *Node) func(d *Dialog) string {
+	return func(d *Dialog) string {
+		if g.IsSelected(d, option) {
+			return selectedMarker
+		}
+		return ""
+	}
+}
+
+/*
+	Endpoint for the options that switches their state
+*/
+func (g *SelectGroup) toggle(e *Node, c *tb.Callback) int {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return Stay
+	}
+	current := g.selection(d)
+	// the set is copied, so it can be read concurrently
+	set := make(map[string]bool, len(current)+1)
+	for id := range current {
+		set[id] = true
+	}
+	if set[e.id] {
+		delete(set, e.id)
+	} else {
+		set[e.id] = true
+	}
+	d.Set(g.key, set)
+	d.setDirty(true)
+	return Forward
+}
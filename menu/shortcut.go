@@ -0,0 +1,63 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"strings"
+)
+
+var keycaps = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+
+/*
+	Numbers the buttons of every page (e.g. "1️⃣ Settings") and lets users type a number instead of tapping a button
+	Text messages have to be passed to Menu.Process, a typed number is handled the same way as a tap on the button
+	Pages are generated on every display while the buttons are numbered
+*/
+func (f *Menu) WithNumbering() *Menu {
+	f.numbered = true
+	return f
+}
+
+/*
+	Gets a label of a button's number
+*/
+func number(i int) string {
+	if i < len(keycaps) {
+		return keycaps[i]
+	}
+	return strconv.Itoa(i+1) + "."
+}
+
+/*
+	Prefixes buttons of a page with their numbers
+*/
+func (f *Menu) numberButtons(buttons []tb.InlineButton) {
+	if !f.numbered {
+		return
+	}
+	for i := range buttons {
+		buttons[i].Text = number(i) + " " + buttons[i].Text
+	}
+}
+
+/*
+	Handles a typed number as a tap on the numbered button of the dialog's page
+	Returns false if the text is not a number of a displayed button
+*/
+func (f *Menu) shortcut(m *tb.Message, d *Dialog) bool {
+	if !f.numbered || d.Position == nil || f.gate.closed() {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(m.Text))
+	if err != nil || n < 1 {
+		return false
+	}
+	nodes, buttons := d.Position.display(d)
+	if n > len(buttons) || buttons[n-1].URL != "" || buttons[n-1].InlineQuery != "" {
+		return false
+	}
+	node, c := nodes[n-1], &tb.Callback{Sender: m.Sender, Message: d.Message}
+	// the press is handled in turn with taps of the dialog the same way as a button's callback
+	d.serialize(func() { node.press(c) })
+	return true
+}
@@ -0,0 +1,135 @@
+package menu
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+	Sets a handler that is called for every open dialog on shutdown,
+	so the dialogs can be persisted (e.g. to a database) and restored after a restart
+*/
+func (f *Menu) OnShutdown(handler func(d *Dialog) error) *Menu {
+	f.mx.Lock()
+	f.onShutdown = handler
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Sets a caption that open menus display after shutdown (e.g. "The bot is restarting")
+	The buttons are removed along with it, an empty caption leaves the menus as they are
+*/
+func (f *Menu) SetShutdownCaption(text string) *Menu {
+	f.mx.Lock()
+	f.shutdownCaption = text
+	f.mx.Unlock()
+	return f
+}
+
+/*
+	Gracefully stops the flow
+	New callbacks and messages are not accepted anymore, running endpoints are waited for,
+	then every open dialog is passed to the shutdown handler, its menu is edited to the shutdown caption
+	and the dialog is closed
+	Returns the context's error if it is done before the flow has stopped,
+	otherwise the first error of the handler or of an edit
+*/
+func (f *Menu) Shutdown(ctx context.Context) error {
+	select {
+	case <-f.gate.close():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	f.SetDialogTTL(0, false)
+	f.mx.RLock()
+	handler, caption := f.onShutdown, f.shutdownCaption
+	f.mx.RUnlock()
+	var first error
+	for _, d := range f.dialogs.remove(func(d *Dialog) bool { return true }) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if handler != nil {
+			err = handler(d)
+		}
+		if err == nil && caption != "" && d.Message != nil {
+			d.setText(caption)
+			_, err = f.edit(d, nil)
+		}
+		d.close()
+		if err != nil {
+			f.fail(err, nil, d.Position)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+/*
+	Tracks running endpoints and stops letting new ones in on shutdown
+*/
+type gate struct {
+	closing bool
+	running int
+	idle    chan struct{}
+	mx      sync.Mutex
+}
+
+/*
+	Lets an endpoint in unless the flow is shutting down
+*/
+func (g *gate) enter() bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.closing {
+		return false
+	}
+	g.running++
+	return true
+}
+
+/*
+	Lets an endpoint out
+*/
+func (g *gate) leave() {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	g.running--
+	if g.closing && g.running == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
+
+/*
+	Checks if the flow is shutting down
+*/
+func (g *gate) closed() bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.closing
+}
+
+/*
+	Stops letting endpoints in
+	Returns a channel that is closed when no endpoints are running
+*/
+func (g *gate) close() <-chan struct{} {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	g.closing = true
+	if g.idle != nil {
+		return g.idle
+	}
+	idle := make(chan struct{})
+	if g.running == 0 {
+		close(idle)
+	} else {
+		g.idle = idle
+	}
+	return idle
+}
@@ -0,0 +1,237 @@
+package menu
+
+import (
+	"github.com/pkg/errors"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+var ErrUnknownPath = errors.New("node does not exist")
+
+/*
+	A state of a dialog that can be stored outside of the process (e.g. as JSON)
+	and restored by another process with the same tree, so conversations survive a deploy
+	Nodes are addressed by their paths relative to the root (see Find), since ids differ between processes
+	Provided nodes are not a part of the tree, so the pages and the buttons of them are not saved
+*/
+type DialogSnapshot struct {
+	Ids        []string
+	Owner      string
+	ChatID     int64
+	MessageID  int
+	Media      bool
+	Language   string
+	Position   string
+	History    []string
+	Pages      map[string]int
+	Disabled   []string
+	Caption    string
+	CaptionKey string
+	Captions   map[string]string
+	Variant    string
+	State      map[string]interface{}
+}
+
+/*
+	Takes snapshots of all open dialogs
+	Values stored in the dialogs are copied as they are, so they have to be serializable
+	to be restored by another process
+*/
+func (f *Menu) ExportDialogs() []DialogSnapshot {
+	f.tree.RLock()
+	paths := make(map[string]string)
+	f.root.treePaths("", paths)
+	f.tree.RUnlock()
+	ids := make(map[*Dialog][]string)
+	f.dialogs.each(func(id string, d *Dialog) {
+		ids[d] = append(ids[d], id)
+	})
+	snapshots := make([]DialogSnapshot, 0, len(ids))
+	for d, keys := range ids {
+		snapshots = append(snapshots, d.snapshot(keys, paths))
+	}
+	return snapshots
+}
+
+/*
+	Restores dialogs from snapshots, replacing the dialogs stored by the same ids
+	The menu messages are not edited until the users press a button
+	Snapshots with a position that does not exist in the tree are skipped, the first such error is returned
+*/
+func (f *Menu) ImportDialogs(snapshots ...DialogSnapshot) error {
+	var first error
+	for _, s := range snapshots {
+		d, err := f.restore(s)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		for _, id := range s.Ids {
+			f.setDialog(id, d)
+		}
+	}
+	return first
+}
+
+/*
+	Takes a snapshot of the dialog stored by specified ids
+*/
+func (d *Dialog) snapshot(ids []string, paths map[string]string) DialogSnapshot {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	s := DialogSnapshot{
+		Ids:        ids,
+		Owner:      d.owner,
+		Language:   d.Language,
+		Caption:    d.text,
+		CaptionKey: d.textKey,
+		Variant:    d.variant,
+		Pages:      make(map[string]int),
+		Captions:   make(map[string]string, len(d.captions)),
+		State:      make(map[string]interface{}, len(d.state)),
+	}
+	if d.Message != nil {
+		s.MessageID = d.Message.ID
+		s.Media = isMedia(d.Message)
+		if d.Message.Chat != nil {
+			s.ChatID = d.Message.Chat.ID
+		}
+	}
+	if d.Position != nil {
+		s.Position = d.Position.visitedPath(d.history)
+	}
+	for i, page := range d.history {
+		s.History = append(s.History, page.visitedPath(d.history[:i]))
+	}
+	for id, page := range d.pages {
+		if path, ok := paths[id]; ok {
+			s.Pages[path] = page
+		}
+	}
+	for id, text := range d.captions {
+		if path, ok := paths[id]; ok {
+			s.Captions[path] = text
+		}
+	}
+	for id := range d.disabled {
+		if path, ok := paths[id]; ok {
+			s.Disabled = append(s.Disabled, path)
+		}
+	}
+	for key, value := range d.state {
+		s.State[key] = value
+	}
+	return s
+}
+
+/*
+	Creates a dialog from a snapshot
+*/
+func (f *Menu) restore(s DialogSnapshot) (*Dialog, error) {
+	find := func(path string) (*Node, error) {
+		node := f.Find(path)
+		if node == nil {
+			return nil, errors.Wrap(ErrUnknownPath, path)
+		}
+		return node, nil
+	}
+	position, err := find(s.Position)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dialog{
+		Language: s.Language,
+		Position: position,
+		owner:    s.Owner,
+		text:     s.Caption,
+		textKey:  s.CaptionKey,
+		variant:  s.Variant,
+		state:    make(map[string]interface{}, len(s.State)),
+	}
+	if s.MessageID != 0 {
+		d.Message = &tb.Message{ID: s.MessageID, Chat: &tb.Chat{ID: s.ChatID}}
+		if s.Media {
+			// only the kind of the message matters for editing it
+			d.Message.Photo = &tb.Photo{}
+		}
+	}
+	for _, path := range s.History {
+		if page, err := find(path); err == nil {
+			d.history = append(d.history, page)
+		}
+	}
+	for path, page := range s.Pages {
+		if node, err := find(path); err == nil {
+			d.setPage(node, page)
+		}
+	}
+	for path, text := range s.Captions {
+		if node, err := find(path); err == nil {
+			if d.captions == nil {
+				d.captions = make(map[string]string)
+			}
+			d.captions[node.id] = text
+		}
+	}
+	for _, path := range s.Disabled {
+		if node, err := find(path); err == nil {
+			d.setEnabled(node, false)
+		}
+	}
+	for key, value := range s.State {
+		d.state[key] = value
+	}
+	d.dirty = false
+	return d, nil
+}
+
+/*
+	Gets a path of texts from the root to the node that Find resolves back to the node
+*/
+func (e *Node) relativePath() string {
+	depth := 0
+	for node := e; node != nil && node.prev != nil; node = node.prev {
+		depth++
+	}
+	texts := make([]string, depth)
+	for node := e; depth > 0; node = node.prev {
+		depth--
+		texts[depth] = node.text
+	}
+	return strings.Join(texts, "/")
+}
+
+/*
+	Gets a path of texts from the root to the node through the pages a dialog has visited before,
+	so Find resolves a mounted node back the way the dialog reached it
+*/
+func (e *Node) visitedPath(history []*Node) string {
+	var texts []string
+	for node := e; node != nil && node.prev != nil; node = node.parentIn(history) {
+		texts = append(texts, node.text)
+	}
+	for i, j := 0, len(texts)-1; i < j; i, j = i+1, j-1 {
+		texts[i], texts[j] = texts[j], texts[i]
+	}
+	return strings.Join(texts, "/")
+}
+
+/*
+	Collects paths that Find resolves to the node and its descendants by their ids
+	A mounted node gets the path it is met by first
+*/
+func (e *Node) treePaths(path string, paths map[string]string) {
+	if _, ok := paths[e.id]; ok {
+		return
+	}
+	paths[e.id] = path
+	for _, child := range e.nodes {
+		if path == "" {
+			child.treePaths(child.text, paths)
+		} else {
+			child.treePaths(path+"/"+child.text, paths)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package menu_test
+
+import (
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestSnapshotOfMountedPage(t *testing.T) {
+	flow, bot := newFlow(t, "snapshot")
+	help := flow.NewNode("help", nil)
+	help.Add("faq", stay).Add("return", flow.HandleBack)
+	flow.GetRoot().AddSub("settings", nil).Add("save", stay).Mount(help)
+	flow.Build("en")
+	user := &tb.User{ID: 1}
+	if err := flow.StartAt(user, "snapshot", "en", flow.GetRoot()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"settings", "settings/help"} {
+		if err := bot.Tap(flow, user, path); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	snapshots := flow.ExportDialogs()
+	if len(snapshots) != 1 || snapshots[0].Position != "settings/help" {
+		t.Fatalf("the dialog is exported at %+v instead of settings/help", snapshots)
+	}
+	if err := flow.ImportDialogs(snapshots...); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range snapshots[0].Ids {
+		d, ok := flow.GetDialog(id)
+		if !ok || d.Position != help {
+			t.Fatalf("the dialog %q is not restored on the mounted page", id)
+		}
+	}
+	if err := bot.Tap(flow, user, "settings/help/return"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Tap(flow, user, "settings/save"); err != nil {
+		t.Fatalf("the restored dialog does not go back to the page it came from: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+/*
+	A handler of callbacks that came from menu messages the flow knows nothing about
+	(e.g. the messages sent before the bot was restarted)
+	The callback is already responded when the handler is called
+*/
+type StaleHandler func(f *Menu, c *tb.Callback)
+
+/*
+	Sets a handler for stale callbacks
+	Callbacks of unknown menu buttons are also delivered to the handler,
+	which takes over the tb.OnCallback endpoint of the bot
+*/
+func (f *Menu) OnStaleCallback(handler StaleHandler) *Menu {
+	f.onStale = handler
+	if handler != nil {
+		f.bot.Handle(tb.OnCallback, f.handleUnknown)
+	}
+	return f
+}
+
+/*
+	Creates a stale callback handler that replaces an old menu with a fresh root menu
+	in the language of the user (see WithLanguageDetector)
+*/
+func ResendRoot(text string) StaleHandler {
+	return func(f *Menu, c *tb.Callback) {
+		if c.Message != nil {
+			f.bot.Delete(c.Message)
+		}
+		f.Start(c.Sender, text, f.languageOf(c.Sender))
+	}
+}
+
+/*
+	Passes a stale callback to the handler
+	Only internal use is intended
+*/
+func (f *Menu) stale(c *tb.Callback) {
+	if f.onStale != nil {
+		f.onStale(f, c)
+	}
+}
+
+/*
+	Handler for callbacks that no button of the bot was registered for
+*/
+func (f *Menu) handleUnknown(c *tb.Callback) {
+	if !strings.Contains(c.Data, uniquePrefix) {
+		// not a menu button
+		return
+	}
+	f.bot.Respond(c)
+	f.stale(c)
+}
@@ -0,0 +1,114 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+)
+
+const (
+	stepperDecrease = "−"
+	stepperIncrease = "+"
+)
+
+/*
+	A node with a page of «− value +» buttons that changes a number within bounds
+	The value is stored in the dialog, the minimum is used until it is changed
+*/
+type Stepper struct {
+	key      string
+	node     *Node
+	min      int
+	max      int
+	step     int
+	onChange func(e *Node, c *tb.Callback, value int)
+}
+
+/*
+	Adds a new node that opens a numeric stepper to the current node
+	Pressing − or + changes the value by the step and calls the callback with the new value,
+	the value never leaves the bounds
+	Returns the new stepper
+*/
+func (e *Node) AddStepper(text string, min, max, step int, onChange func(e *Node, c *tb.Callback, value int)) *Stepper {
+	s := &Stepper{min: min, max: max, step: step, onChange: onChange}
+	s.node = e.AddSub(text, nil)
+	s.node.SetColumns(3)
+	s.node.AddSub(stepperDecrease, s.change(-step)).raw = true
+	value := s.node.AddSub(text, s.stay)
+	value.raw = true
+	value.labelFunc = func(d *Dialog, lang string) string {
+		return strconv.Itoa(s.Value(d))
+	}
+	s.node.AddSub(stepperIncrease, s.change(step)).raw = true
+	s.key = "stepper_" + s.node.id
+	return s
+}
+
+/*
+	Get the node that opens the stepper
+*/
+func (s *Stepper) GetNode() *Node {
+	return s.node
+}
+
+/*
+	Gets the value of the stepper in a dialog
+*/
+func (s *Stepper) Value(d *Dialog) int {
+	if value, ok := d.Get(s.key); ok {
+		return value.(int)
+	}
+	return s.min
+}
+
+/*
+	Sets the value of the stepper in a dialog without calling the callback
+	The value is clamped to the bounds, the change is displayed the next time the menu is updated
+*/
+func (s *Stepper) SetValue(d *Dialog, value int) {
+	d.Set(s.key, s.clamp(value))
+	d.setDirty(true)
+}
+
+/*
+	Keeps a value within the bounds of the stepper
+*/
+func (s *Stepper) clamp(value int) int {
+	if value < s.min {
+		return s.min
+	}
+	if value > s.max {
+		return s.max
+	}
+	return value
+}
+
+/*
+	Creates an endpoint that changes the value by a delta
+*/
+func (s *Stepper) change(delta int) Callback {
+	return func(e *Node, c *tb.Callback) int {
+		d, ok := e.flow.dialog(c)
+		if !ok {
+			return Stay
+		}
+		prev := s.Value(d)
+		value := s.clamp(prev + delta)
+		if value == prev {
+			// already at the bound
+			return Stay
+		}
+		s.SetValue(d, value)
+		if s.onChange != nil {
+			s.onChange(e, c, value)
+		}
+		return Forward
+	}
+}
+
+/*
+	Endpoint for the button that displays the value
+*/
+func (s *Stepper) stay(e *Node, c *tb.Callback) int {
+	return Stay
+}
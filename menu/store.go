@@ -0,0 +1,136 @@
+package menu
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const storeShards = 32
+
+/*
+	A registry of dialogs split into shards guarded by their own locks,
+	so callbacks of different users rarely wait for each other
+*/
+type dialogStore struct {
+	shards [storeShards]dialogShard
+}
+
+/*
+	A part of the registry that holds dialogs of some users
+*/
+type dialogShard struct {
+	dialogs map[string]*Dialog
+	mx      sync.RWMutex
+}
+
+/*
+	Creates an empty registry
+*/
+func newDialogStore() *dialogStore {
+	s := &dialogStore{}
+	for i := range s.shards {
+		s.shards[i].dialogs = make(map[string]*Dialog)
+	}
+	return s
+}
+
+/*
+	Gets a shard that holds the dialog of a user
+*/
+func (s *dialogStore) shard(id string) *dialogShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &s.shards[h.Sum32()%storeShards]
+}
+
+/*
+	Retrieves a dialog by a user id
+*/
+func (s *dialogStore) get(id string) (*Dialog, bool) {
+	shard := s.shard(id)
+	shard.mx.RLock()
+	d, ok := shard.dialogs[id]
+	shard.mx.RUnlock()
+	return d, ok
+}
+
+/*
+	Stores a dialog by a user id
+*/
+func (s *dialogStore) set(id string, d *Dialog) {
+	shard := s.shard(id)
+	shard.mx.Lock()
+	shard.dialogs[id] = d
+	shard.mx.Unlock()
+}
+
+/*
+	Deletes a dialog by a user id
+*/
+func (s *dialogStore) delete(id string) {
+	shard := s.shard(id)
+	shard.mx.Lock()
+	delete(shard.dialogs, id)
+	shard.mx.Unlock()
+}
+
+/*
+	Removes and returns all dialogs that match a predicate
+	A dialog stored by several ids is returned once
+	Shards are locked one at a time
+*/
+func (s *dialogStore) remove(match func(d *Dialog) bool) []*Dialog {
+	removed := make([]*Dialog, 0)
+	seen := make(map[*Dialog]bool)
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mx.Lock()
+		for id, d := range shard.dialogs {
+			if match(d) {
+				if !seen[d] {
+					seen[d] = true
+					removed = append(removed, d)
+				}
+				delete(shard.dialogs, id)
+			}
+		}
+		shard.mx.Unlock()
+	}
+	return removed
+}
+
+/*
+	Returns all dialogs that match a predicate without removing them
+	A dialog stored by several ids is returned once
+*/
+func (s *dialogStore) list(match func(d *Dialog) bool) []*Dialog {
+	found := make([]*Dialog, 0)
+	seen := make(map[*Dialog]bool)
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mx.RLock()
+		for _, d := range shard.dialogs {
+			if !seen[d] && match(d) {
+				seen[d] = true
+				found = append(found, d)
+			}
+		}
+		shard.mx.RUnlock()
+	}
+	return found
+}
+
+/*
+	Calls a function for every id a dialog is stored by
+	Shards are locked one at a time, so the function must not change the registry
+*/
+func (s *dialogStore) each(fn func(id string, d *Dialog)) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mx.RLock()
+		for id, d := range shard.dialogs {
+			fn(id, d)
+		}
+		shard.mx.RUnlock()
+	}
+}
@@ -0,0 +1,170 @@
+package survey
+
+/*
+	Surveys and quizzes that are built on top of menu flows
+	Author: Daniil Furmanov
+	License: MIT
+*/
+
+import (
+	"go-telegram-flow/menu"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+)
+
+/*
+	An option of a question, the scores of the chosen options are summed up
+*/
+type Option struct {
+	Text  string
+	Score int
+}
+
+/*
+	Answers of a user along with the total score of the chosen options
+	Answers are the texts of the chosen options in the order of the questions
+*/
+type Result struct {
+	Answers []string
+	Score   int
+}
+
+/*
+	A sequence of questions that are asked one per page
+	The answers are collected in the dialog and passed to the completion callback after the last question
+*/
+type Survey struct {
+	key        string
+	node       *menu.Node
+	questions  []*question
+	done       string
+	onComplete func(e *menu.Node, c *tb.Callback, result Result)
+}
+
+/*
+	A question along with the node that displays its options
+*/
+type question struct {
+	text    string
+	node    *menu.Node
+	options []Option
+}
+
+/*
+	Adds a new node that starts a survey to a parent node
+	The node itself displays the first question, every following question gets a hidden node of its own
+	Questions have to be added before the flow is built
+	Returns the new survey
+*/
+func AddSurvey(parent *menu.Node, text string, onComplete func(e *menu.Node, c *tb.Callback, result Result)) *Survey {
+	s := &Survey{done: "Thank you!", onComplete: onComplete}
+	s.node = parent.AddSub(text, s.begin)
+	s.key = "survey_" + s.node.GetId()
+	return s
+}
+
+/*
+	Get the node that starts the survey
+*/
+func (s *Survey) GetNode() *menu.Node {
+	return s.node
+}
+
+/*
+	Sets a caption that is displayed on the parent page when the survey is completed
+	Returns the survey
+*/
+func (s *Survey) SetCompletion(text string) *Survey {
+	s.done = text
+	return s
+}
+
+/*
+	Adds a question with options that are displayed as is
+	Returns the survey
+*/
+func (s *Survey) AddQuestion(text string, options ...Option) *Survey {
+	n := len(s.questions)
+	q := &question{text: text, node: s.node, options: options}
+	if n > 0 {
+		q.node = s.node.AddSub("question"+strconv.Itoa(n), nil).
+			SetLabelFunc(label(text)).
+			SetVisible(hidden)
+	}
+	for i, option := range options {
+		q.node.AddSub("option"+strconv.Itoa(i), menu.Navigating(s.answer(n, i))).SetLabelFunc(label(option.Text))
+	}
+	s.questions = append(s.questions, q)
+	return s
+}
+
+/*
+	Gets the result collected in a dialog so far
+*/
+func (s *Survey) Collected(d *menu.Dialog) Result {
+	if value, ok := d.Get(s.key); ok {
+		return value.(Result)
+	}
+	return Result{}
+}
+
+/*
+	Endpoint of the survey's node that starts the survey from the first question
+*/
+func (s *Survey) begin(e *menu.Node, c *tb.Callback) int {
+	d, ok := e.GetFlow().DialogOf(c)
+	if !ok || len(s.questions) == 0 {
+		return menu.Stay
+	}
+	d.Delete(s.key)
+	d.SetCaption(s.node, s.questions[0].text)
+	return menu.Forward
+}
+
+/*
+	Creates an endpoint that records an option of a question and asks the next question
+	The last answer completes the survey and takes the user back to the parent page
+*/
+func (s *Survey) answer(n, i int) menu.NavCallback {
+	return func(e *menu.Node, c *tb.Callback) menu.NavigationResult {
+		d, ok := e.GetFlow().DialogOf(c)
+		if !ok {
+			return menu.Nav()
+		}
+		result := s.Collected(d)
+		if len(result.Answers) != n {
+			// an option of a question that was answered already
+			return menu.Nav()
+		}
+		option := s.questions[n].options[i]
+		result.Answers = append(append([]string(nil), result.Answers...), option.Text)
+		result.Score += option.Score
+		if n+1 < len(s.questions) {
+			d.Set(s.key, result)
+			d.SetCaption(s.questions[n+1].node, s.questions[n+1].text)
+			return menu.Nav().GotoNode(s.questions[n+1].node)
+		}
+		d.Delete(s.key)
+		if s.onComplete != nil {
+			s.onComplete(e, c, result)
+		}
+		d.SetCaption(s.node.Previous(), s.done)
+		return menu.Nav().GotoNode(s.node.Previous())
+	}
+}
+
+/*
+	Hides nodes of the following questions from the page of the first one
+*/
+func hidden(e *menu.Node, d *menu.Dialog) bool {
+	return false
+}
+
+/*
+	Creates a label function that returns a constant text
+*/
+func label(text string) func(d *menu.Dialog, lang string) string {
+	return func(d *menu.Dialog, lang string) string {
+		return text
+	}
+}
@@ -0,0 +1,104 @@
+package menu
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+	A turn of a dialog that taps and background updates (e.g. refreshes and broadcasts) take one at a time
+	Functions that come while the dialog is busy are queued and called by the holder of the turn,
+	so a tap that starts an update of its own dialog does not wait for itself
+*/
+type turn struct {
+	busy   bool
+	queued []func()
+	mx     sync.Mutex
+}
+
+/*
+	Calls a function in turn with other updates of the dialog
+	Returns false if the dialog was busy and the function was queued
+	Only internal use is intended
+*/
+func (d *Dialog) serialize(fn func()) bool {
+	t := &d.turn
+	t.mx.Lock()
+	if t.busy {
+		t.queued = append(t.queued, fn)
+		t.mx.Unlock()
+		return false
+	}
+	t.busy = true
+	t.mx.Unlock()
+	defer func() {
+		if fn != nil {
+			// a panic leaves the rest of the queue to the next holder of the turn
+			t.mx.Lock()
+			t.busy = false
+			t.mx.Unlock()
+		}
+	}()
+	for fn != nil {
+		fn()
+		t.mx.Lock()
+		if len(t.queued) > 0 {
+			fn, t.queued = t.queued[0], t.queued[1:]
+		} else {
+			fn, t.busy = nil, false
+		}
+		t.mx.Unlock()
+	}
+	return true
+}
+
+/*
+	Calls a function in turn with other updates of the dialog and waits until it is called
+	Must not be called while the same goroutine holds the turn (e.g. from an endpoint)
+	Only internal use is intended
+*/
+func (d *Dialog) exclusive(fn func()) {
+	done := make(chan struct{})
+	if !d.serialize(func() {
+		defer close(done)
+		fn()
+	}) {
+		<-done
+	}
+}
+
+/*
+	Calls a function in turn with other updates of the dialog after a delay
+	Only internal use is intended
+*/
+func (d *Dialog) after(delay time.Duration, fn func()) {
+	time.AfterFunc(delay, func() {
+		d.serialize(fn)
+	})
+}
+
+/*
+	Calls a function that changes a dialog in turn with other updates of the dialog
+	Returns the error of the function if it is called before the call returns,
+	otherwise the function is queued and its error is passed to the error handler of the flow
+	Only internal use is intended
+*/
+func (f *Menu) inTurn(d *Dialog, fn func() error) error {
+	var err error
+	var mx sync.Mutex
+	waiting := true
+	d.serialize(func() {
+		e := fn()
+		mx.Lock()
+		defer mx.Unlock()
+		if waiting {
+			err = e
+		} else if e != nil {
+			f.fail(e, nil, d.Position)
+		}
+	})
+	mx.Lock()
+	defer mx.Unlock()
+	waiting = false
+	return err
+}
@@ -0,0 +1,177 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Makes dialogs remember up to a limit of steps, so they can be undone and redone (see Node.Undo)
+	A step is taken every time an endpoint is called, it holds the page, the caption and the values of the dialog
+	Maps and slices of basic types stored in the dialog are copied, other values are remembered as they are,
+	so they have to be replaced instead of being changed in place to be undone
+	Zero limit disables undo
+*/
+func (f *Menu) WithUndo(limit int) *Menu {
+	f.undoLimit = limit
+	return f
+}
+
+/*
+	A state of a dialog before an endpoint was called
+*/
+type step struct {
+	position *Node
+	text     string
+	textKey  string
+	state    map[string]interface{}
+}
+
+/*
+	Takes the dialog a step back to the state it had before the last endpoint was called and updates the menu
+	It is meant to be called from endpoints, the call of the endpoint itself is not remembered as a step
+	Does nothing if there are no steps to undo
+*/
+func (e *Node) Undo(c *tb.Callback) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	d.mx.Lock()
+	if len(d.undo) == 0 {
+		d.undone = true
+		d.mx.Unlock()
+		return nil
+	}
+	prev := d.undo[len(d.undo)-1]
+	d.undo = d.undo[:len(d.undo)-1]
+	d.redo = append(d.redo, d.step())
+	d.mx.Unlock()
+	return e.revert(c, d, prev)
+}
+
+/*
+	Takes the dialog to the state that the last Undo has reverted and updates the menu
+	Steps that can be redone are forgotten when an endpoint is called
+	Does nothing if there are no steps to redo
+*/
+func (e *Node) Redo(c *tb.Callback) error {
+	d, ok := e.flow.dialog(c)
+	if !ok {
+		return ErrDialogNotFound
+	}
+	d.mx.Lock()
+	if len(d.redo) == 0 {
+		d.undone = true
+		d.mx.Unlock()
+		return nil
+	}
+	next := d.redo[len(d.redo)-1]
+	d.redo = d.redo[:len(d.redo)-1]
+	d.undo = append(d.undo, d.step())
+	d.mx.Unlock()
+	return e.revert(c, d, next)
+}
+
+/*
+	Checks if the dialog has steps to undo
+*/
+func (d *Dialog) CanUndo() bool {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return len(d.undo) > 0
+}
+
+/*
+	Checks if the dialog has steps to redo
+*/
+func (d *Dialog) CanRedo() bool {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return len(d.redo) > 0
+}
+
+/*
+	Restores a step of the dialog and displays its page
+*/
+func (e *Node) revert(c *tb.Callback, d *Dialog, s step) error {
+	d.mx.Lock()
+	d.undone = true
+	d.text, d.textKey = s.text, s.textKey
+	d.state = s.state
+	d.mx.Unlock()
+	page := s.position
+	if page == nil {
+		page = e.flow.root
+	}
+	return e.update(c.Sender, d, page)
+}
+
+/*
+	Gets the current state of the dialog as a step
+	The dialog has to be locked by the caller
+*/
+func (d *Dialog) step() step {
+	state := make(map[string]interface{}, len(d.state))
+	for key, value := range d.state {
+		state[key] = copyValue(value)
+	}
+	return step{position: d.Position, text: d.text, textKey: d.textKey, state: state}
+}
+
+/*
+	Remembers the state of the dialog before an endpoint is called
+	Returns a function that keeps the step after the endpoint unless the endpoint has undone or redone a step
+*/
+func (f *Menu) remember(d *Dialog) func() {
+	if f.undoLimit <= 0 {
+		return func() {}
+	}
+	d.mx.Lock()
+	s := d.step()
+	d.undone = false
+	d.mx.Unlock()
+	return func() {
+		d.mx.Lock()
+		defer d.mx.Unlock()
+		if d.undone {
+			d.undone = false
+			return
+		}
+		d.undo = append(d.undo, s)
+		if len(d.undo) > f.undoLimit {
+			d.undo = d.undo[len(d.undo)-f.undoLimit:]
+		}
+		d.redo = nil
+	}
+}
+
+/*
+	Copies maps and slices of basic types, so changes made in place are not seen by a remembered step
+*/
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]bool:
+		c := make(map[string]bool, len(v))
+		for key, value := range v {
+			c[key] = value
+		}
+		return c
+	case map[string]int:
+		c := make(map[string]int, len(v))
+		for key, value := range v {
+			c[key] = value
+		}
+		return c
+	case map[string]string:
+		c := make(map[string]string, len(v))
+		for key, value := range v {
+			c[key] = value
+		}
+		return c
+	case []string:
+		return append([]string(nil), v...)
+	case []int:
+		return append([]int(nil), v...)
+	}
+	return value
+}
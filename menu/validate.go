@@ -0,0 +1,105 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	An error that lists all problems found in a menu tree
+*/
+type ValidationError struct {
+	Problems []string
+	cyclic   bool
+}
+
+/*
+	Lists the problems one per line
+*/
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid menu tree (%d problems):\n\t%s", len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+/*
+	Checks the tree of the flow in every built locale (or in the default locale of the engine if none are built)
+	and reports sibling nodes with the same label, missing translation keys, cycles
+	and leaves with neither an endpoint nor a link
+	Returns nil or *ValidationError
+*/
+func (f *Menu) Validate() error {
+	langs := f.GetLocales()
+	if len(langs) < 1 {
+		langs = []string{f.engine.DefaultLocale}
+	}
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	problems := &ValidationError{}
+	for _, lang := range langs {
+		f.validate(lang, problems)
+	}
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+/*
+	Gets the result of the validation made by the last build (see Build)
+	Returns nil if the tree was valid
+*/
+func (f *Menu) Err() error {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+	return f.err
+}
+
+/*
+	Checks the tree in a locale and adds the problems to the error
+	The tree has to be locked by the caller
+*/
+func (f *Menu) validate(lang string, problems *ValidationError) {
+	f.root.validate(f.id, lang, make(map[*Node]bool), problems)
+}
+
+/*
+	Checks the node and its children in a locale
+	Paths are computed the same way as by the build, since a tree with a cycle can not be built
+*/
+func (e *Node) validate(path, lang string, parents map[*Node]bool, problems *ValidationError) {
+	add := func(format string, args ...interface{}) {
+		problems.Problems = append(problems.Problems, fmt.Sprintf("%s [%s]: ", path, lang)+fmt.Sprintf(format, args...))
+	}
+	parents[e] = true
+	defer delete(parents, e)
+	if e.prev != nil && len(e.nodes) == 0 && e.provider == nil && e.endpoint == nil &&
+		e.url == "" && e.query == "" && e.input == nil && e.payment == nil {
+		add("the node has neither an endpoint nor children")
+	}
+	if problem := e.oversized(); problem != "" {
+		add("%s", problem)
+	}
+	labels := make(map[string]*Node, len(e.nodes))
+	for _, child := range e.nodes {
+		childPath := e.childPath(child, path) + "/" + child.text
+		if parents[child] {
+			add("%s is an ancestor of the node", child.text)
+			problems.cyclic = true
+			continue
+		}
+		if !child.raw && child.labelFunc == nil && child.title == "" {
+			key := childPath
+			if child.labelKey != "" {
+				key = e.flow.id + "/" + child.labelKey
+			}
+			label := e.flow.engine.Lang(lang).Tr(key)
+			if label == "" || label == key {
+				add("missing translation key %s", key)
+			} else if other, ok := labels[label]; ok && (other.experiment == nil || other.experiment != child.experiment) {
+				add("%s and %s have the same label %q", other.text, child.text, label)
+			} else {
+				labels[label] = child
+			}
+		}
+		child.validate(childPath, lang, parents, problems)
+	}
+}
@@ -0,0 +1,98 @@
+package menu
+
+import (
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+/*
+	Builds an alternative set of markups (e.g. "premium" and "free") where only the nodes accepted by the filter are displayed
+	The variant is built for all built locales and for the ones built later,
+	a dialog displays it after Dialog.SetVariant, so the filter is not called on every display
+	Building a variant with the same name replaces it
+*/
+func (f *Menu) BuildVariant(name string, filter func(*Node) bool) *Menu {
+	f.tree.Lock()
+	defer f.tree.Unlock()
+	if f.variants == nil {
+		f.variants = make(map[string]func(*Node) bool)
+	}
+	f.variants[name] = filter
+	for _, lang := range f.GetLocales() {
+		f.root.buildVariant(name, lang, filter)
+	}
+	return f
+}
+
+/*
+	Makes the dialog display a variant of the menu, an empty name displays the full menu
+	The change is displayed the next time the menu is updated
+*/
+func (d *Dialog) SetVariant(name string) {
+	d.mx.Lock()
+	d.variant = name
+	d.mx.Unlock()
+	d.setDirty(true)
+}
+
+/*
+	Gets the name of the variant the dialog displays
+*/
+func (d *Dialog) GetVariant() string {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	return d.variant
+}
+
+/*
+	Gets the filter of the variant that a dialog displays
+	Returns nil if the dialog displays the full menu
+*/
+func (f *Menu) variant(d *Dialog) func(*Node) bool {
+	name := d.GetVariant()
+	if name == "" {
+		return nil
+	}
+	f.tree.RLock()
+	defer f.tree.RUnlock()
+	return f.variants[name]
+}
+
+/*
+	Builds markups of a variant for the node and its children
+	The tree has to be locked by the caller and built for the locale
+*/
+func (e *Node) buildVariant(name, lang string, filter func(*Node) bool) {
+	buttons, ok := e.buttons[lang]
+	if !ok {
+		return
+	}
+	for _, child := range e.nodes {
+		child.buildVariant(name, lang, filter)
+	}
+	e.markups[variantKey(name, lang)] = e.filtered(lang, buttons, filter)
+}
+
+/*
+	Creates a markup of the node's page with the buttons of the children that pass a filter
+*/
+func (e *Node) filtered(lang string, buttons []tb.InlineButton, filter func(*Node) bool) *tb.ReplyMarkup {
+	nodes := make([]*Node, 0, len(e.nodes))
+	filtered := make([]tb.InlineButton, 0, len(buttons))
+	for i, child := range e.nodes {
+		if filter(child) {
+			nodes = append(nodes, child)
+			filtered = append(filtered, buttons[i])
+		}
+	}
+	_, filtered = e.ordered(nil, nodes, filtered)
+	return &tb.ReplyMarkup{
+		InlineKeyboard: e.decorate(lang, e.arrange(filtered)),
+	}
+}
+
+/*
+	Gets a key of markups of a variant in a locale
+*/
+func variantKey(name, lang string) string {
+	return name + "/" + lang
+}
@@ -0,0 +1,220 @@
+package widgets
+
+/*
+	Widgets that are built on top of menu flows
+	Author: Daniil Furmanov
+	License: MIT
+*/
+
+import (
+	"go-telegram-flow/menu"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
+	"time"
+)
+
+const calendarCells = 42
+
+/*
+	A node with a month view of days that lets a user pick a date
+	The picked date is stored in the dialog and the user is taken back to the parent page
+*/
+type Calendar struct {
+	key      string
+	node     *menu.Node
+	months   []string
+	weekdays []string
+	onPick   func(e *menu.Node, c *tb.Callback, date time.Time)
+}
+
+/*
+	Adds a new node that opens a calendar to a parent node
+	The callback is called with the picked date (at midnight UTC), nil callbacks are allowed
+	Returns the new calendar
+*/
+func AddCalendar(parent *menu.Node, text string, onPick func(e *menu.Node, c *tb.Callback, date time.Time)) *Calendar {
+	cal := &Calendar{
+		months:   []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"},
+		onPick:   onPick,
+	}
+	cal.node = parent.AddSub(text, nil).SetLayout(calendarLayout)
+	cal.key = "calendar_" + cal.node.GetId()
+	cal.node.AddSub("prev", cal.turn(-1)).SetLabelFunc(label("«"))
+	cal.node.AddSub("month", stay).SetLabelFunc(cal.title)
+	cal.node.AddSub("next", cal.turn(1)).SetLabelFunc(label("»"))
+	for i := 0; i < 7; i++ {
+		cal.node.AddSub("weekday"+strconv.Itoa(i), stay).SetLabelFunc(cal.weekday(i))
+	}
+	for i := 0; i < calendarCells; i++ {
+		cal.node.AddSub("day"+strconv.Itoa(i), cal.pick(i)).
+			SetLabelFunc(cal.day(i)).
+			SetVisible(cal.week(i))
+	}
+	return cal
+}
+
+/*
+	Get the node that opens the calendar
+*/
+func (cal *Calendar) GetNode() *menu.Node {
+	return cal.node
+}
+
+/*
+	Sets names of the months (starting with January) and of the weekdays (starting with Monday)
+	Returns the calendar
+*/
+func (cal *Calendar) SetNames(months, weekdays []string) *Calendar {
+	if len(months) == 12 {
+		cal.months = months
+	}
+	if len(weekdays) == 7 {
+		cal.weekdays = weekdays
+	}
+	return cal
+}
+
+/*
+	Gets the date picked in a dialog
+	Returns false if no date was picked
+*/
+func (cal *Calendar) Picked(d *menu.Dialog) (time.Time, bool) {
+	if value, ok := d.Get(cal.key); ok {
+		return value.(time.Time), true
+	}
+	return time.Time{}, false
+}
+
+/*
+	Gets the first day of the month displayed in a dialog
+	The month of the picked date or the current month is displayed by default
+*/
+func (cal *Calendar) month(d *menu.Dialog) time.Time {
+	if value, ok := d.Get(cal.key + "_month"); ok {
+		return value.(time.Time)
+	}
+	date, ok := cal.Picked(d)
+	if !ok {
+		date = time.Now().UTC()
+	}
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+/*
+	Gets a date of a cell in a dialog
+	Returns false if the cell is outside of the displayed month
+*/
+func (cal *Calendar) date(d *menu.Dialog, cell int) (time.Time, bool) {
+	month := cal.month(d)
+	offset := (int(month.Weekday()) + 6) % 7
+	date := month.AddDate(0, 0, cell-offset)
+	return date, date.Month() == month.Month()
+}
+
+/*
+	Creates an endpoint that switches the displayed month by a delta
+*/
+func (cal *Calendar) turn(delta int) menu.Callback {
+	return func(e *menu.Node, c *tb.Callback) int {
+		d, ok := e.GetFlow().DialogOf(c)
+		if !ok {
+			return menu.Stay
+		}
+		d.Set(cal.key+"_month", cal.month(d).AddDate(0, delta, 0))
+		d.Refresh()
+		return menu.Forward
+	}
+}
+
+/*
+	Creates an endpoint that picks the date of a cell
+*/
+func (cal *Calendar) pick(cell int) menu.Callback {
+	return func(e *menu.Node, c *tb.Callback) int {
+		d, ok := e.GetFlow().DialogOf(c)
+		if !ok {
+			return menu.Stay
+		}
+		date, ok := cal.date(d, cell)
+		if !ok {
+			return menu.Stay
+		}
+		d.Set(cal.key, date)
+		d.Delete(cal.key + "_month")
+		if cal.onPick != nil {
+			cal.onPick(e, c, date)
+		}
+		return menu.Back
+	}
+}
+
+/*
+	Creates a label function for a cell
+*/
+func (cal *Calendar) day(cell int) func(d *menu.Dialog, lang string) string {
+	return func(d *menu.Dialog, lang string) string {
+		date, ok := cal.date(d, cell)
+		if !ok {
+			return " "
+		}
+		if picked, ok := cal.Picked(d); ok && picked.Equal(date) {
+			return "[" + strconv.Itoa(date.Day()) + "]"
+		}
+		return strconv.Itoa(date.Day())
+	}
+}
+
+/*
+	Creates a predicate that hides cells of the weeks that are not a part of the displayed month
+*/
+func (cal *Calendar) week(cell int) func(e *menu.Node, d *menu.Dialog) bool {
+	return func(e *menu.Node, d *menu.Dialog) bool {
+		_, first := cal.date(d, cell-cell%7)
+		_, last := cal.date(d, cell-cell%7+6)
+		return first || last
+	}
+}
+
+/*
+	Gets a title of the displayed month
+*/
+func (cal *Calendar) title(d *menu.Dialog, lang string) string {
+	month := cal.month(d)
+	return cal.months[month.Month()-1] + " " + strconv.Itoa(month.Year())
+}
+
+/*
+	Creates a label function for a weekday
+*/
+func (cal *Calendar) weekday(i int) func(d *menu.Dialog, lang string) string {
+	return func(d *menu.Dialog, lang string) string {
+		return cal.weekdays[i]
+	}
+}
+
+/*
+	Creates a label function that returns a constant text
+*/
+func label(text string) func(d *menu.Dialog, lang string) string {
+	return func(d *menu.Dialog, lang string) string {
+		return text
+	}
+}
+
+/*
+	Endpoint for buttons that only display a text
+*/
+func stay(e *menu.Node, c *tb.Callback) int {
+	return menu.Stay
+}
+
+/*
+	Arranges the navigation in the first row and days of the week in the following rows
+*/
+func calendarLayout(buttons []tb.InlineButton) [][]tb.InlineButton {
+	if len(buttons) < 3 {
+		return menu.SingleColumn(buttons)
+	}
+	return append([][]tb.InlineButton{buttons[:3]}, menu.Columns(7)(buttons[3:])...)
+}
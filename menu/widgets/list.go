@@ -0,0 +1,119 @@
+package widgets
+
+import (
+	"go-telegram-flow/menu"
+	tb "gopkg.in/tucnak/telebot.v2"
+	"strings"
+)
+
+/*
+	A paginated list of items with a search button that filters the items by a typed query
+	The query is stored in the dialog until it is cleared
+*/
+type List struct {
+	key    string
+	node   *menu.Node
+	items  []*menu.Node
+	search *menu.Node
+	clear  *menu.Node
+}
+
+/*
+	Adds a new node that opens a list of items to a parent node
+	Pressing an item calls the callback with the item's text,
+	the result of the callback is treated as a result of a node's callback
+	The search button is displayed at the top of the first page
+	Returns the new list
+*/
+func AddList(parent *menu.Node, text string, items []string, pageSize int, onPick func(e *menu.Node, c *tb.Callback, item string) int) *List {
+	l := &List{items: make([]*menu.Node, len(items))}
+	flow := parent.GetFlow()
+	l.node = parent.AddSub(text, nil).SetPageSize(pageSize)
+	l.key = "list_" + l.node.GetId()
+	pick := func(e *menu.Node, c *tb.Callback) int {
+		return onPick(e, c, e.GetText())
+	}
+	for i, item := range items {
+		l.items[i] = flow.NewNode(item, pick)
+	}
+	l.search = flow.NewNode("🔍 Search", nil).AwaitText("Type a part of the name", l.filter)
+	l.clear = flow.NewNode("✖ Clear the search", l.reset)
+	l.node.SetProvider(menu.NodeProviderFunc(l.nodes))
+	return l
+}
+
+/*
+	Get the node that opens the list
+*/
+func (l *List) GetNode() *menu.Node {
+	return l.node
+}
+
+/*
+	Sets labels of the search and the clear buttons and a prompt that asks for a query
+	Must be called before the flow is built
+	Returns the list
+*/
+func (l *List) SetSearchLabels(search, clear, prompt string) *List {
+	flow := l.node.GetFlow()
+	l.search = flow.NewNode(search, nil).AwaitText(prompt, l.filter)
+	l.clear = flow.NewNode(clear, l.reset)
+	return l
+}
+
+/*
+	Gets the query that the list is filtered by in a dialog
+*/
+func (l *List) Query(d *menu.Dialog) string {
+	if value, ok := d.Get(l.key); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+/*
+	Provides the search buttons and the items that match the query of a dialog
+*/
+func (l *List) nodes(e *menu.Node, d *menu.Dialog) []*menu.Node {
+	query := strings.ToLower(l.Query(d))
+	nodes := make([]*menu.Node, 0, len(l.items)+2)
+	nodes = append(nodes, l.search)
+	if query != "" {
+		nodes = append(nodes, l.clear)
+	}
+	for _, item := range l.items {
+		if query == "" || strings.Contains(strings.ToLower(item.GetText()), query) {
+			nodes = append(nodes, item)
+		}
+	}
+	return nodes
+}
+
+/*
+	Text handler that stores a query
+*/
+func (l *List) filter(e *menu.Node, m *tb.Message) int {
+	d, ok := e.GetFlow().DialogOfMessage(m)
+	if !ok {
+		return menu.Back
+	}
+	if query := strings.TrimSpace(m.Text); query != "" {
+		d.Set(l.key, query)
+	} else {
+		d.Delete(l.key)
+	}
+	return menu.Forward
+}
+
+/*
+	Endpoint that clears the query
+*/
+func (l *List) reset(e *menu.Node, c *tb.Callback) int {
+	d, ok := e.GetFlow().DialogOf(c)
+	if !ok {
+		return menu.Stay
+	}
+	d.Delete(l.key)
+	d.Refresh()
+	return menu.Forward
+}
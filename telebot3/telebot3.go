@@ -0,0 +1,488 @@
+package telebot3
+
+/*
+	Adapter that allows to drive flows with telebot v3
+	Author: Daniil Furmanov
+	License: MIT
+*/
+
+import (
+	"github.com/pkg/errors"
+	tele "gopkg.in/telebot.v3"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var ErrUnsupported = errors.New("the value is not supported by the adapter")
+
+/*
+	Adapter wraps a telebot v3 bot, so it can be used everywhere a menu.Bot is expected
+	Values are converted between v2 and v3 in both directions
+*/
+type Adapter struct {
+	bot *tele.Bot
+}
+
+/*
+	Creates a new adapter for a telebot v3 bot
+*/
+func NewAdapter(bot *tele.Bot) *Adapter {
+	return &Adapter{bot: bot}
+}
+
+/*
+	Get the wrapped telebot v3 bot
+*/
+func (a *Adapter) GetBot() *tele.Bot {
+	return a.bot
+}
+
+/*
+	Registers a v2 handler (func(*tb.Callback), func(*tb.Message), func(*tb.Query),
+	func(*tb.ChosenInlineResult) or func(*tb.PreCheckoutQuery)) as a v3 context handler
+*/
+func (a *Adapter) Handle(endpoint interface{}, handler interface{}) {
+	switch e := endpoint.(type) {
+	case *tb.InlineButton:
+		endpoint = &tele.InlineButton{Unique: e.Unique}
+	case *tb.ReplyButton:
+		endpoint = &tele.ReplyButton{Text: e.Text}
+	case string:
+		switch e {
+		case tb.OnChosenInlineResult:
+			endpoint = tele.OnInlineResult
+		case tb.OnCheckout:
+			endpoint = tele.OnCheckout
+		case tb.OnPayment:
+			endpoint = tele.OnPayment
+		}
+	}
+	// other string endpoints (commands and events) are the same in both versions
+	switch h := handler.(type) {
+	case func(*tb.Callback):
+		a.bot.Handle(endpoint, func(c tele.Context) error {
+			h(callback(c.Callback()))
+			return nil
+		})
+	case func(*tb.Message):
+		a.bot.Handle(endpoint, func(c tele.Context) error {
+			h(message(c.Message()))
+			return nil
+		})
+	case func(*tb.Query):
+		a.bot.Handle(endpoint, func(c tele.Context) error {
+			h(query(c.Query()))
+			return nil
+		})
+	case func(*tb.ChosenInlineResult):
+		a.bot.Handle(endpoint, func(c tele.Context) error {
+			h(inlineResult(c.InlineResult()))
+			return nil
+		})
+	case func(*tb.PreCheckoutQuery):
+		a.bot.Handle(endpoint, func(c tele.Context) error {
+			h(checkoutQuery(c.PreCheckoutQuery()))
+			return nil
+		})
+	default:
+		panic("telebot3: unsupported handler type")
+	}
+}
+
+/*
+	Sends a message with v2 options
+*/
+func (a *Adapter) Send(to tb.Recipient, what interface{}, options ...interface{}) (*tb.Message, error) {
+	value, err := sendable(what)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := a.bot.Send(recipient(to.Recipient()), value, convertOptions(options)...)
+	return message(msg), err
+}
+
+/*
+	Edits a message with v2 options
+*/
+func (a *Adapter) Edit(msg tb.Editable, what interface{}, options ...interface{}) (*tb.Message, error) {
+	value, err := sendable(what)
+	if err != nil {
+		return nil, err
+	}
+	newMsg, err := a.bot.Edit(editable(msg), value, convertOptions(options)...)
+	return message(newMsg), err
+}
+
+/*
+	Edits a caption of a media message with v2 options
+*/
+func (a *Adapter) EditCaption(msg tb.Editable, caption string, options ...interface{}) (*tb.Message, error) {
+	newMsg, err := a.bot.EditCaption(editable(msg), caption, convertOptions(options)...)
+	return message(newMsg), err
+}
+
+/*
+	Replaces media of a message with v2 options
+*/
+func (a *Adapter) EditMedia(msg tb.Editable, media tb.InputMedia, options ...interface{}) (*tb.Message, error) {
+	value, err := sendable(media)
+	if err != nil {
+		return nil, err
+	}
+	input, ok := value.(tele.Inputtable)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	newMsg, err := a.bot.EditMedia(editable(msg), input, convertOptions(options)...)
+	return message(newMsg), err
+}
+
+/*
+	Deletes a message
+*/
+func (a *Adapter) Delete(msg tb.Editable) error {
+	return a.bot.Delete(editable(msg))
+}
+
+/*
+	Responds to a callback
+*/
+func (a *Adapter) Respond(c *tb.Callback, response ...*tb.CallbackResponse) error {
+	responses := make([]*tele.CallbackResponse, len(response))
+	for i, r := range response {
+		responses[i] = &tele.CallbackResponse{
+			CallbackID: r.CallbackID,
+			Text:       r.Text,
+			ShowAlert:  r.ShowAlert,
+			URL:        r.URL,
+		}
+	}
+	return a.bot.Respond(&tele.Callback{ID: c.ID}, responses...)
+}
+
+/*
+	Answers an inline query with v2 results
+	Only article results are supported
+*/
+func (a *Adapter) Answer(q *tb.Query, resp *tb.QueryResponse) error {
+	results := make(tele.Results, len(resp.Results))
+	for i, r := range resp.Results {
+		article, ok := r.(*tb.ArticleResult)
+		if !ok {
+			return ErrUnsupported
+		}
+		result := &tele.ArticleResult{Title: article.Title, Text: article.Text, Description: article.Description}
+		result.SetResultID(article.ResultID())
+		results[i] = result
+	}
+	return a.bot.Answer(&tele.Query{ID: q.ID}, &tele.QueryResponse{
+		QueryID:    resp.QueryID,
+		Results:    results,
+		CacheTime:  resp.CacheTime,
+		IsPersonal: resp.IsPersonal,
+		NextOffset: resp.NextOffset,
+	})
+}
+
+/*
+	Accepts or rejects a pre-checkout query, the order is rejected when an error message is given
+*/
+func (a *Adapter) Accept(q *tb.PreCheckoutQuery, errorMessage ...string) error {
+	return a.bot.Accept(&tele.PreCheckoutQuery{ID: q.ID}, errorMessage...)
+}
+
+/*
+	A recipient that is addressed by its string identificator
+*/
+type recipient string
+
+func (r recipient) Recipient() string {
+	return string(r)
+}
+
+/*
+	Converts a v2 message reference to v3
+*/
+func editable(msg tb.Editable) tele.Editable {
+	id, chat := msg.MessageSig()
+	return tele.StoredMessage{MessageID: id, ChatID: chat}
+}
+
+/*
+	Converts a value to be sent or edited to v3
+*/
+func sendable(what interface{}) (interface{}, error) {
+	switch v := what.(type) {
+	case string:
+		return v, nil
+	case *tb.Location:
+		return &tele.Location{Lat: v.Lat, Lng: v.Lng, LivePeriod: v.LivePeriod}, nil
+	case *tb.Photo:
+		return &tele.Photo{File: file(v.File), Caption: v.Caption}, nil
+	case *tb.Document:
+		return &tele.Document{File: file(v.File), Caption: v.Caption, MIME: v.MIME, FileName: v.FileName}, nil
+	case *tb.Video:
+		return &tele.Video{
+			File:     file(v.File),
+			Width:    v.Width,
+			Height:   v.Height,
+			Duration: v.Duration,
+			Caption:  v.Caption,
+			MIME:     v.MIME,
+			FileName: v.FileName,
+		}, nil
+	case *tb.Audio:
+		return &tele.Audio{File: file(v.File), Duration: v.Duration, Caption: v.Caption, MIME: v.MIME, FileName: v.FileName}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+/*
+	Converts v2 send options to v3
+*/
+func convertOptions(options []interface{}) []interface{} {
+	converted := make([]interface{}, 0, len(options))
+	for _, option := range options {
+		switch v := option.(type) {
+		case *tb.ReplyMarkup:
+			if v != nil {
+				converted = append(converted, markup(v))
+			}
+		case *tb.SendOptions:
+			if v != nil {
+				opts := &tele.SendOptions{
+					DisableWebPagePreview: v.DisableWebPagePreview,
+					DisableNotification:   v.DisableNotification,
+					ParseMode:             tele.ParseMode(v.ParseMode),
+				}
+				if v.ReplyMarkup != nil {
+					opts.ReplyMarkup = markup(v.ReplyMarkup)
+				}
+				converted = append(converted, opts)
+			}
+		case tb.ParseMode:
+			converted = append(converted, tele.ParseMode(v))
+		case tb.Option:
+			switch v {
+			case tb.NoPreview:
+				converted = append(converted, tele.NoPreview)
+			case tb.Silent:
+				converted = append(converted, tele.Silent)
+			case tb.ForceReply:
+				converted = append(converted, tele.ForceReply)
+			case tb.OneTimeKeyboard:
+				converted = append(converted, tele.OneTimeKeyboard)
+			}
+		}
+	}
+	return converted
+}
+
+/*
+	Converts a v2 markup to v3
+*/
+func markup(m *tb.ReplyMarkup) *tele.ReplyMarkup {
+	result := &tele.ReplyMarkup{
+		ForceReply:      m.ForceReply,
+		ResizeKeyboard:  m.ResizeReplyKeyboard,
+		OneTimeKeyboard: m.OneTimeKeyboard,
+		RemoveKeyboard:  m.ReplyKeyboardRemove,
+		Selective:       m.Selective,
+	}
+	if m.InlineKeyboard != nil {
+		result.InlineKeyboard = make([][]tele.InlineButton, len(m.InlineKeyboard))
+		for i, row := range m.InlineKeyboard {
+			result.InlineKeyboard[i] = make([]tele.InlineButton, len(row))
+			for j, btn := range row {
+				result.InlineKeyboard[i][j] = tele.InlineButton{
+					Unique:          btn.Unique,
+					Text:            btn.Text,
+					URL:             btn.URL,
+					Data:            btn.Data,
+					InlineQuery:     btn.InlineQuery,
+					InlineQueryChat: btn.InlineQueryChat,
+				}
+			}
+		}
+	}
+	if m.ReplyKeyboard != nil {
+		result.ReplyKeyboard = make([][]tele.ReplyButton, len(m.ReplyKeyboard))
+		for i, row := range m.ReplyKeyboard {
+			result.ReplyKeyboard[i] = make([]tele.ReplyButton, len(row))
+			for j, btn := range row {
+				result.ReplyKeyboard[i][j] = tele.ReplyButton{
+					Text:     btn.Text,
+					Contact:  btn.Contact,
+					Location: btn.Location,
+				}
+			}
+		}
+	}
+	return result
+}
+
+/*
+	Converts a v2 file to v3
+*/
+func file(f tb.File) tele.File {
+	return tele.File{FileID: f.FileID, UniqueID: f.UniqueID, FileSize: int64(f.FileSize), FileLocal: f.FileLocal, FileURL: f.FileURL}
+}
+
+/*
+	Converts a v3 file to v2
+*/
+func fileOf(f tele.File) tb.File {
+	return tb.File{FileID: f.FileID, UniqueID: f.UniqueID, FileSize: int(f.FileSize), FilePath: f.FilePath}
+}
+
+/*
+	Converts a v3 user to v2
+*/
+func user(u *tele.User) *tb.User {
+	if u == nil {
+		return nil
+	}
+	return &tb.User{
+		ID:           int(u.ID),
+		FirstName:    u.FirstName,
+		LastName:     u.LastName,
+		Username:     u.Username,
+		LanguageCode: u.LanguageCode,
+		IsBot:        u.IsBot,
+	}
+}
+
+/*
+	Converts a v3 chat to v2
+*/
+func chat(c *tele.Chat) *tb.Chat {
+	if c == nil {
+		return nil
+	}
+	return &tb.Chat{
+		ID:        c.ID,
+		Type:      tb.ChatType(c.Type),
+		Title:     c.Title,
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Username:  c.Username,
+	}
+}
+
+/*
+	Converts a v3 message to v2
+*/
+func message(m *tele.Message) *tb.Message {
+	if m == nil {
+		return nil
+	}
+	msg := &tb.Message{
+		ID:       m.ID,
+		Sender:   user(m.Sender),
+		Unixtime: m.Unixtime,
+		Chat:     chat(m.Chat),
+		Text:     m.Text,
+		Caption:  m.Caption,
+		Payload:  m.Payload,
+	}
+	if m.Location != nil {
+		msg.Location = &tb.Location{Lat: m.Location.Lat, Lng: m.Location.Lng, LivePeriod: m.Location.LivePeriod}
+	}
+	if m.Contact != nil {
+		msg.Contact = &tb.Contact{
+			PhoneNumber: m.Contact.PhoneNumber,
+			FirstName:   m.Contact.FirstName,
+			LastName:    m.Contact.LastName,
+			UserID:      int(m.Contact.UserID),
+		}
+	}
+	if m.Payment != nil {
+		msg.Payment = &tb.Payment{
+			Currency:         m.Payment.Currency,
+			Total:            m.Payment.Total,
+			Payload:          m.Payment.Payload,
+			OptionID:         m.Payment.OptionID,
+			TelegramChargeID: m.Payment.TelegramChargeID,
+			ProviderChargeID: m.Payment.ProviderChargeID,
+		}
+	}
+	if m.Photo != nil {
+		msg.Photo = &tb.Photo{File: fileOf(m.Photo.File), Width: m.Photo.Width, Height: m.Photo.Height, Caption: m.Photo.Caption}
+	}
+	if m.Document != nil {
+		msg.Document = &tb.Document{File: fileOf(m.Document.File), Caption: m.Document.Caption, MIME: m.Document.MIME, FileName: m.Document.FileName}
+	}
+	if m.Video != nil {
+		msg.Video = &tb.Video{File: fileOf(m.Video.File), Width: m.Video.Width, Height: m.Video.Height, Caption: m.Video.Caption}
+	}
+	if m.Audio != nil {
+		msg.Audio = &tb.Audio{File: fileOf(m.Audio.File), Duration: m.Audio.Duration, Caption: m.Audio.Caption}
+	}
+	return msg
+}
+
+/*
+	Converts a v3 callback to v2
+*/
+func callback(c *tele.Callback) *tb.Callback {
+	if c == nil {
+		return nil
+	}
+	return &tb.Callback{
+		ID:        c.ID,
+		Sender:    user(c.Sender),
+		Message:   message(c.Message),
+		MessageID: c.MessageID,
+		Data:      c.Data,
+	}
+}
+
+/*
+	Converts a v3 inline query to v2
+*/
+func query(q *tele.Query) *tb.Query {
+	if q == nil {
+		return nil
+	}
+	converted := &tb.Query{ID: q.ID, Text: q.Text, Offset: q.Offset}
+	if q.Sender != nil {
+		converted.From = *user(q.Sender)
+	}
+	if q.Location != nil {
+		converted.Location = &tb.Location{Lat: q.Location.Lat, Lng: q.Location.Lng}
+	}
+	return converted
+}
+
+/*
+	Converts a v3 chosen inline result to v2
+*/
+func inlineResult(r *tele.InlineResult) *tb.ChosenInlineResult {
+	if r == nil {
+		return nil
+	}
+	converted := &tb.ChosenInlineResult{ResultID: r.ResultID, Query: r.Query, MessageID: r.MessageID}
+	if r.Sender != nil {
+		converted.From = *user(r.Sender)
+	}
+	if r.Location != nil {
+		converted.Location = &tb.Location{Lat: r.Location.Lat, Lng: r.Location.Lng}
+	}
+	return converted
+}
+
+/*
+	Converts a v3 pre-checkout query to v2
+*/
+func checkoutQuery(q *tele.PreCheckoutQuery) *tb.PreCheckoutQuery {
+	if q == nil {
+		return nil
+	}
+	return &tb.PreCheckoutQuery{
+		Sender:   user(q.Sender),
+		ID:       q.ID,
+		Currency: q.Currency,
+		Payload:  q.Payload,
+		Total:    q.Total,
+		OptionID: q.OptionID,
+	}
+}